@@ -20,10 +20,10 @@ import (
 	"io"
 	"strings"
 	"text/scanner"
-	"unicode"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
 )
 
@@ -209,7 +209,7 @@ func initScanner(s string) (*scanner.Scanner, func(error) error) {
 		return err
 	}
 	sc.Error = func(_ *scanner.Scanner, msg string) {
-		e := errors.E(nil, msg)
+		e := errors.E(nil, "%s", msg)
 		e = errors.E(e, "while scaning: %q", s)
 		report(e)
 	}
@@ -268,11 +268,11 @@ func (t *initTree) print(w *bufio.Writer, g *generator, ind int) error {
 
 		var closeBrace bool
 		if oneof, ok := oneofs[k]; ok {
-			fmt.Fprintf(w, "%s: &%s.%s_%s{\n", snakeToCapCamel(oneof), impSpec.Name, desc.GetName(), snakeToCapCamel(k))
+			fmt.Fprintf(w, "%s: &%s.%s_%s{\n", naming.CamelCase(oneof), impSpec.Name, desc.GetName(), naming.CamelCase(k))
 			closeBrace = true
 			indent(ind + 2)
 		}
-		w.WriteString(snakeToCapCamel(k))
+		w.WriteString(naming.CamelCase(k))
 
 		w.WriteString(": ")
 		if err := t.vals[i].print(w, g, ind+1); err != nil {
@@ -290,19 +290,3 @@ func (t *initTree) print(w *bufio.Writer, g *generator, ind int) error {
 	w.WriteString("}")
 	return nil
 }
-
-func snakeToCapCamel(s string) string {
-	var sb strings.Builder
-	cap := true
-	for _, r := range s {
-		if r == '_' {
-			cap = true
-		} else if cap {
-			cap = false
-			sb.WriteRune(unicode.ToUpper(r))
-		} else {
-			sb.WriteRune(r)
-		}
-	}
-	return sb.String()
-}
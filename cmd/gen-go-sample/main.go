@@ -31,6 +31,7 @@ import (
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/googleapis/gapic-generator-go/internal/errors"
 	"github.com/googleapis/gapic-generator-go/internal/license"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
 	"github.com/googleapis/gapic-generator-go/internal/printer"
 	yaml "gopkg.in/yaml.v2"
@@ -314,7 +315,7 @@ func (g *generator) genSample(ifaceName, methName, regTag string, valSet SampleV
 		// TODO(pongad): some types, like int32, are not supported by flag package.
 		// We have to convert.
 		typ := pbinfo.GoTypeForPrim[argTrees[i].typ.prim]
-		p(`%s := flag.%s(%q, %s, "")`, name, snakeToCapCamel(typ), name, argTrees[i].leafVal)
+		p(`%s := flag.%s(%q, %s, "")`, name, naming.CamelCase(typ), name, argTrees[i].leafVal)
 	}
 
 	p("  flag.Parse()")
@@ -0,0 +1,87 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/gapic-generator-go/internal/diff"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/license"
+)
+
+// checkFiles compares the generated files with the files in outDir, and writes a unified diff
+// of each file that differs to w. A file missing from outDir differs from the generated one.
+// It reports whether any file differs.
+// Files of outDir that are not generated are not compared, since outDir may contain other files.
+//
+// The copyright year of the generated files is the current year, so it is not compared.
+func checkFiles(w io.Writer, outDir string, files []outFile) (bool, error) {
+	drift := false
+	for _, f := range files {
+		fname := filepath.Join(outDir, filepath.FromSlash(f.name))
+		oldName := fname
+		b, err := ioutil.ReadFile(fname)
+		if os.IsNotExist(err) {
+			oldName = "/dev/null"
+		} else if err != nil {
+			return false, errors.User(err, "cannot read checked-in file")
+		}
+
+		old := string(b)
+		d := diff.Unified(oldName, fname, old, withYearOf(f.content, old))
+		if d == "" {
+			continue
+		}
+		drift = true
+		if _, err := io.WriteString(w, d); err != nil {
+			return false, err
+		}
+	}
+	return drift, nil
+}
+
+// copyrightPrefix is the start of the license header of generated files, followed by the year.
+var copyrightPrefix = license.Apache[:strings.Index(license.Apache, "%d")]
+
+// withYearOf returns content with the copyright year of the license header of old,
+// if both start with a license header.
+func withYearOf(content, old string) string {
+	year, ok := copyrightYear(old)
+	if !ok {
+		return content
+	}
+	if cur, ok := copyrightYear(content); ok {
+		return copyrightPrefix + year + content[len(copyrightPrefix)+len(cur):]
+	}
+	return content
+}
+
+// copyrightYear returns the year of the license header s starts with.
+func copyrightYear(s string) (string, bool) {
+	if !strings.HasPrefix(s, copyrightPrefix) {
+		return "", false
+	}
+	s = s[len(copyrightPrefix):]
+	n := 0
+	for n < len(s) && '0' <= s[n] && s[n] <= '9' {
+		n++
+	}
+	return s[:n], n > 0
+}
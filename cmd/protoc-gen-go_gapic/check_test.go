@@ -0,0 +1,107 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := generate(writeDescSet(t, dir), "package-path=example.com/foo/apiv1", []string{"example/foo.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(dir, "out")
+	if err := writeFiles(outDir, files); err != nil {
+		t.Fatal(err)
+	}
+	pkgDir := filepath.Join(outDir, "example.com", "foo", "apiv1")
+
+	check := func(name string, wantDrift bool, wantDiff ...string) {
+		t.Helper()
+		var buf bytes.Buffer
+		drift, err := checkFiles(&buf, outDir, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if drift != wantDrift {
+			t.Errorf("%s: drift = %t, want %t\n%s", name, drift, wantDrift, buf.String())
+		}
+		for _, want := range wantDiff {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s: cannot find %q in\n%s", name, want, buf.String())
+			}
+		}
+	}
+	// edit replaces old by new in file f of the package.
+	edit := func(f, old, new string) {
+		t.Helper()
+		fname := filepath.Join(pkgDir, f)
+		b, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := string(b)
+		if !strings.Contains(s, old) {
+			t.Fatalf("cannot find %q in %s", old, f)
+		}
+		if err := ioutil.WriteFile(fname, []byte(strings.Replace(s, old, new, 1)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check("generated", false)
+
+	year, ok := copyrightYear(files[0].content)
+	if !ok {
+		t.Fatalf("cannot find copyright year in\n%s", files[0].content)
+	}
+	edit("doc.go", "Copyright "+year, "Copyright 2001")
+	check("other copyright year", false)
+
+	edit("foo_client.go", "package apiv1", "package foo")
+	check("edited", true, "--- "+filepath.Join(pkgDir, "foo_client.go"), "-package foo\n+package apiv1\n")
+
+	if err := os.Remove(filepath.Join(pkgDir, "gapic_metadata.json")); err != nil {
+		t.Fatal(err)
+	}
+	check("missing", true, "--- /dev/null\n+++ "+filepath.Join(pkgDir, "gapic_metadata.json"))
+}
+
+func TestWithYearOf(t *testing.T) {
+	for _, tst := range []struct {
+		name, content, old, want string
+	}{
+		{"same year", "// Copyright 2019 Google LLC\nx\n", "// Copyright 2019 Google LLC\ny\n", "// Copyright 2019 Google LLC\nx\n"},
+		{"other year", "// Copyright 2019 Google LLC\nx\n", "// Copyright 2001 Google LLC\ny\n", "// Copyright 2001 Google LLC\nx\n"},
+		{"old without header", "// Copyright 2019 Google LLC\nx\n", "y\n", "// Copyright 2019 Google LLC\nx\n"},
+		{"content without header", "{}\n", "// Copyright 2001 Google LLC\ny\n", "{}\n"},
+	} {
+		if got := withYearOf(tst.content, tst.old); got != tst.want {
+			t.Errorf("%s: got %q, want %q", tst.name, got, tst.want)
+		}
+	}
+}
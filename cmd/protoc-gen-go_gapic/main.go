@@ -15,16 +15,59 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 
 	"github.com/golang/protobuf/proto"
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
 	"github.com/googleapis/gapic-generator-go/internal/gengapic"
 )
 
+// driftStatus is the exit status of -check when the generated files differ from those on disk.
+// It is distinct from the status of errors, 1, and of invalid flags, 2.
+const driftStatus = 3
+
+// When given a descriptor set with -desc, the generator runs standalone,
+// generating the files named as arguments without protoc.
+// Otherwise, it is a protoc plugin reading its request from stdin.
 func main() {
+	descFname := flag.String("desc", "", "run standalone: generate from this FileDescriptorSet, as written by protoc --descriptor_set_out --include_imports")
+	outDir := flag.String("out", ".", "standalone: directory the generated files are written to")
+	opt := flag.String("opt", "", "standalone: generator options, the same as --go_gapic_opt")
+	check := flag.Bool("check", false, fmt.Sprintf("standalone: instead of writing the generated files, print how the files in the output directory differ from them, "+
+		"and exit with status %d if they do; files of the output directory that are not generated, such as the client of a removed service, are not reported", driftStatus))
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s -desc file.desc [-out dir] [-opt options] [-check] file.proto...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       protoc --go_gapic_out=dir ...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *descFname != "" {
+		files, err := generate(*descFname, *opt, flag.Args())
+		if err != nil {
+			fatal(err)
+		}
+		if !*check {
+			if err := writeFiles(*outDir, files); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		drift, err := checkFiles(os.Stdout, *outDir, files)
+		if err != nil {
+			fatal(err)
+		}
+		if drift {
+			os.Exit(driftStatus)
+		}
+		return
+	}
+
 	reqBytes, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
 		log.Fatal(err)
@@ -35,8 +78,11 @@ func main() {
 	}
 
 	genResp, err := gengapic.Gen(&genReq)
-	if err != nil {
-		log.Fatal(err)
+	if errors.IsUser(err) {
+		// The input cannot be generated; protoc reports the error to the user.
+		genResp = &plugin.CodeGeneratorResponse{Error: proto.String(err.Error())}
+	} else if err != nil {
+		log.Fatalf("internal error: %v\n%s", err, errors.Stack(err))
 	}
 
 	outBytes, err := proto.Marshal(genResp)
@@ -47,3 +93,13 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// fatal reports err and exits.
+// User errors are printed alone, so that tools can parse their positions.
+func fatal(err error) {
+	if errors.IsUser(err) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.Fatalf("internal error: %v\n%s", err, errors.Stack(err))
+}
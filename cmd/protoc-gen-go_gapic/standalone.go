@@ -0,0 +1,116 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/gengapic"
+)
+
+// generate generates the files in the descriptor set read from descFname, like protoc would.
+func generate(descFname, opt string, files []string) ([]outFile, error) {
+	genReq, err := readRequest(descFname, opt, files)
+	if err != nil {
+		return nil, err
+	}
+	genResp, err := gengapic.Gen(genReq)
+	if err != nil {
+		return nil, err
+	}
+	return outputFiles(genResp.GetFile())
+}
+
+// readRequest builds the request protoc would send to generate files,
+// from the descriptor set read from descFname and the options opt.
+func readRequest(descFname, opt string, files []string) (*plugin.CodeGeneratorRequest, error) {
+	if len(files) == 0 {
+		return nil, errors.User(nil, "no proto file to generate")
+	}
+
+	descBytes, err := ioutil.ReadFile(descFname)
+	if err != nil {
+		return nil, errors.User(err, "cannot read proto descriptor file")
+	}
+	var desc descriptor.FileDescriptorSet
+	if err := proto.Unmarshal(descBytes, &desc); err != nil {
+		return nil, errors.User(err, "error reading proto descriptor file")
+	}
+
+	// protoc lists the files in dependency order, as do descriptor sets written with --include_imports.
+	inSet := map[string]bool{}
+	for _, f := range desc.GetFile() {
+		inSet[f.GetName()] = true
+	}
+	for _, f := range files {
+		if !inSet[f] {
+			return nil, errors.User(nil, "file %q is not in descriptor set %s", f, descFname)
+		}
+	}
+
+	genReq := &plugin.CodeGeneratorRequest{
+		FileToGenerate: files,
+		ProtoFile:      desc.GetFile(),
+	}
+	if opt != "" {
+		genReq.Parameter = proto.String(opt)
+	}
+	return genReq, nil
+}
+
+// outFile is a file written by the generator.
+type outFile struct {
+	name, content string
+}
+
+// outputFiles returns the files of a response in order, each with its whole content:
+// as protoc does, it appends the content of a file without a name to the previous file.
+func outputFiles(files []*plugin.CodeGeneratorResponse_File) ([]outFile, error) {
+	var out []outFile
+	for _, f := range files {
+		if f.GetInsertionPoint() != "" {
+			return nil, errors.E(nil, "insertion points are not supported: %s", f.GetInsertionPoint())
+		}
+		if f.GetName() == "" {
+			if len(out) == 0 {
+				return nil, errors.E(nil, "first generated file has no name")
+			}
+			out[len(out)-1].content += f.GetContent()
+			continue
+		}
+		out = append(out, outFile{name: f.GetName(), content: f.GetContent()})
+	}
+	return out, nil
+}
+
+// writeFiles writes the generated files to outDir, creating directories as needed.
+func writeFiles(outDir string, files []outFile) error {
+	for _, f := range files {
+		fname := filepath.Join(outDir, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			return errors.User(err, "cannot create output directory")
+		}
+		if err := ioutil.WriteFile(fname, []byte(f.content), 0644); err != nil {
+			return errors.User(err, "cannot write generated file")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// writeDescSet writes a descriptor set with a single service to a temporary directory,
+// and returns the name of the file.
+func writeDescSet(t *testing.T, dir string) string {
+	fileOpts := &descriptor.FileOptions{GoPackage: proto.String("example.com/foo/foopb;foopb")}
+	if err := proto.SetExtension(fileOpts, annotations.E_Metadata, &annotations.Metadata{
+		ProductName:      "Foo",
+		PackageNamespace: []string{"Example"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	servOpts := &descriptor.ServiceOptions{}
+	if err := proto.SetExtension(servOpts, annotations.E_DefaultHost, proto.String("foo.example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	desc := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("example/foo.proto"),
+			Package: proto.String("example.foo"),
+			Options: fileOpts,
+			MessageType: []*descriptor.DescriptorProto{
+				{Name: proto.String("Request")},
+				{Name: proto.String("Response")},
+			},
+			Service: []*descriptor.ServiceDescriptorProto{{
+				Name:    proto.String("FooService"),
+				Options: servOpts,
+				Method: []*descriptor.MethodDescriptorProto{{
+					Name:       proto.String("GetThing"),
+					InputType:  proto.String(".example.foo.Request"),
+					OutputType: proto.String(".example.foo.Response"),
+				}},
+			}},
+		}},
+	}
+	b, err := proto.Marshal(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(dir, "foo.desc")
+	if err := ioutil.WriteFile(fname, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func TestStandalone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "standalone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	descFname := writeDescSet(t, dir)
+	outDir := filepath.Join(dir, "out")
+	files, err := generate(descFname, "package-path=example.com/foo/apiv1", []string{"example/foo.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles(outDir, files); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []string{"foo_client.go", "doc.go", "foo_client_example_test.go", "gapic_metadata.json"} {
+		b, err := ioutil.ReadFile(filepath.Join(outDir, "example.com", "foo", "apiv1", f))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if strings.HasSuffix(f, ".go") && !strings.Contains(string(b), "package apiv1") {
+			t.Errorf("%s: cannot find package clause in\n%s", f, b)
+		}
+		// The body of the client follows its header.
+		if f == "foo_client.go" && !strings.Contains(string(b), ") GetThing(") {
+			t.Errorf("%s: cannot find method GetThing in\n%s", f, b)
+		}
+	}
+}
+
+func TestReadRequestErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "standalone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	descFname := writeDescSet(t, dir)
+	garbage := filepath.Join(dir, "garbage.desc")
+	if err := ioutil.WriteFile(garbage, []byte("not a descriptor set"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tst := range []struct {
+		name      string
+		descFname string
+		files     []string
+	}{
+		{"no file", descFname, nil},
+		{"missing descriptor set", filepath.Join(dir, "missing.desc"), []string{"example/foo.proto"}},
+		{"not a descriptor set", garbage, []string{"example/foo.proto"}},
+		{"file not in set", descFname, []string{"example/bar.proto"}},
+	} {
+		_, err := readRequest(tst.descFname, "", tst.files)
+		if err == nil {
+			t.Errorf("%s: readRequest succeeded, want error", tst.name)
+		} else if !errors.IsUser(err) {
+			t.Errorf("%s: IsUser(%v) = false, want true", tst.name, err)
+		}
+	}
+
+	genReq, err := readRequest(descFname, "package-path=example.com/foo/apiv1", []string{"example/foo.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := genReq.GetParameter(), "package-path=example.com/foo/apiv1"; got != want {
+		t.Errorf("Parameter = %q, want %q", got, want)
+	}
+	if got := len(genReq.GetProtoFile()); got != 1 {
+		t.Errorf("got %d proto files, want 1", got)
+	}
+}
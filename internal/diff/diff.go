@@ -0,0 +1,253 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff computes line-based differences between texts, in the unified format of diff -u.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines printed around changes.
+const context = 3
+
+// Unified returns the differences between texts a and b, named aName and bName,
+// in unified format, or the empty string if they are the same.
+func Unified(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := edits(lines(a), lines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	// Number of lines of a and b before ops[i].
+	aBefore, bBefore, i := 0, 0, 0
+	for _, h := range hunks(ops) {
+		for ; i < h.start; i++ {
+			if ops[i].kind != '+' {
+				aBefore++
+			}
+			if ops[i].kind != '-' {
+				bBefore++
+			}
+		}
+		h.write(&sb, ops, aBefore, bBefore)
+	}
+	return sb.String()
+}
+
+// lines splits s into lines, keeping their newlines.
+func lines(s string) []string {
+	l := strings.SplitAfter(s, "\n")
+	if l[len(l)-1] == "" {
+		l = l[:len(l)-1]
+	}
+	return l
+}
+
+// op is a line of the edit script: kept (' '), deleted from a ('-') or inserted from b ('+').
+type op struct {
+	kind byte
+	line string
+}
+
+// edits returns the shortest edit script turning a into b.
+//
+// It uses the linear space variant of Myers' algorithm: the middle snake of the shortest path,
+// found by searching from both ends at once, splits the problem in two halves solved recursively.
+// The memory used is proportional to the number of lines, however different the texts are.
+func edits(a, b []string) []op {
+	n := len(a) + len(b)
+	d := differ{
+		a:  a,
+		b:  b,
+		vf: make([]int, 2*n+2),
+		vb: make([]int, 2*n+2),
+		// The search on diagonal k is at v[off+k], with -n <= k <= n.
+		off: n + 1,
+	}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type differ struct {
+	a, b []string
+
+	// Furthest points reached on each diagonal by the forward and backward searches.
+	// They are shared by the successive searches for the middle snake.
+	vf, vb []int
+	off    int
+
+	ops []op
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi] to d.ops.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Common prefix and suffix.
+	pre := 0
+	for aLo+pre < aHi && bLo+pre < bHi && d.a[aLo+pre] == d.b[bLo+pre] {
+		pre++
+	}
+	suf := 0
+	for aHi-suf > aLo+pre && bHi-suf > bLo+pre && d.a[aHi-suf-1] == d.b[bHi-suf-1] {
+		suf++
+	}
+	for i := 0; i < pre; i++ {
+		d.ops = append(d.ops, op{' ', d.a[aLo+i]})
+	}
+	aLo, bLo = aLo+pre, bLo+pre
+
+	switch {
+	case aLo == aHi-suf:
+		for _, l := range d.b[bLo : bHi-suf] {
+			d.ops = append(d.ops, op{'+', l})
+		}
+	case bLo == bHi-suf:
+		for _, l := range d.a[aLo : aHi-suf] {
+			d.ops = append(d.ops, op{'-', l})
+		}
+	default:
+		x, y, u, v := d.middleSnake(aLo, aHi-suf, bLo, bHi-suf)
+		d.compare(aLo, x, bLo, y)
+		for _, l := range d.a[x:u] {
+			d.ops = append(d.ops, op{' ', l})
+		}
+		d.compare(u, aHi-suf, v, bHi-suf)
+	}
+
+	for _, l := range d.a[aHi-suf : aHi] {
+		d.ops = append(d.ops, op{' ', l})
+	}
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the middle snake of a shortest path
+// turning a[aLo:aHi] into b[bLo:bHi], which must not be empty and must differ at both ends.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	vf, vb, off := d.vf, d.vb, d.off
+	vf[off+1], vb[off+1] = 0, 0
+
+	for D := 0; D <= (n+m+1)/2; D++ {
+		// Forward search, from the start. x and y are offsets from aLo and bLo.
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			// The backward search on the same diagonal is delta-k, searched up to round D-1.
+			if c := delta - k; odd && -(D-1) <= c && c <= D-1 && x+vb[off+c] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+
+		// Backward search, from the end. x and y are offsets back from aHi and bHi.
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vb[off+k-1] < vb[off+k+1]) {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			if c := delta - k; !odd && -D <= c && c <= D && x+vf[off+c] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
+
+// hunk is the range [start, end) of an edit script printed together.
+type hunk struct {
+	start, end int
+}
+
+// hunks groups the changes of ops with their context.
+// Changes separated by at most twice the context are in the same hunk.
+func hunks(ops []op) []hunk {
+	var hs []hunk
+	for i, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if len(hs) > 0 && start <= hs[len(hs)-1].end {
+			hs[len(hs)-1].end = end
+		} else {
+			hs = append(hs, hunk{start, end})
+		}
+	}
+	return hs
+}
+
+// write writes the hunk header and lines, given the number of lines of a and b before the hunk.
+func (h hunk) write(sb *strings.Builder, ops []op, aBefore, bBefore int) {
+	var aLen, bLen int
+	for _, o := range ops[h.start:h.end] {
+		if o.kind != '+' {
+			aLen++
+		}
+		if o.kind != '-' {
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aBefore, aLen), hunkRange(bBefore, bLen))
+
+	for _, o := range ops[h.start:h.end] {
+		sb.WriteByte(o.kind)
+		sb.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the range of lines of a hunk, given the number of lines before it.
+// An empty range is designated by the line before it.
+func hunkRange(before, n int) string {
+	start := before + 1
+	if n == 0 {
+		start = before
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	for _, tst := range []struct {
+		name, a, b, want string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{
+			"change",
+			"1\n2\n3\n4\n5\n6\n7\n8\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n",
+			"--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			"--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			"joined hunks",
+			"1\n2\n3\n4\n5\n6\n7\n",
+			"one\n2\n3\n4\n5\n6\nseven\n",
+			"--- a\n+++ b\n@@ -1,7 +1,7 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n-7\n+seven\n",
+		},
+		{"from empty", "", "a\nb\n", "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"to empty", "a\n", "", "--- a\n+++ b\n@@ -1 +0,0 @@\n-a\n"},
+		{
+			"no newline at end",
+			"a\nb",
+			"a\nb\n",
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	} {
+		if got := Unified("a", "b", tst.a, tst.b); got != tst.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tst.name, got, tst.want)
+		}
+	}
+}
+
+// check verifies that ops is an edit script turning a into b.
+func check(t *testing.T, name string, a, b []string, ops []op) {
+	t.Helper()
+	var gotA, gotB []string
+	for _, o := range ops {
+		if o.kind != '+' {
+			gotA = append(gotA, o.line)
+		}
+		if o.kind != '-' {
+			gotB = append(gotB, o.line)
+		}
+	}
+	if !reflect.DeepEqual(gotA, a) || !reflect.DeepEqual(gotB, b) {
+		t.Errorf("%s: the edit script does not turn a into b", name)
+	}
+}
+
+func TestEditsLarge(t *testing.T) {
+	const n = 20000
+	var a, edited, other []string
+	for i := 0; i < n; i++ {
+		a = append(a, fmt.Sprintf("line %d\n", i))
+		if i%100 == 0 {
+			edited = append(edited, fmt.Sprintf("edited line %d\n", i))
+		} else {
+			edited = append(edited, a[i])
+		}
+		other = append(other, fmt.Sprintf("other line %d\n", i))
+	}
+
+	for _, tst := range []struct {
+		name string
+		a, b []string
+		// Number of changed lines of the shortest edit script.
+		changes int
+	}{
+		{"new file", nil, a, n},
+		{"removed file", a, nil, n},
+		{"edited", a, edited, 2 * n / 100},
+		// The time grows as the product of the size and the number of changes.
+		{"rewritten", a[:n/10], other[:n/10], 2 * n / 10},
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		ops := edits(tst.a, tst.b)
+		runtime.ReadMemStats(&after)
+
+		check(t, tst.name, tst.a, tst.b, ops)
+		changes := 0
+		for _, o := range ops {
+			if o.kind != ' ' {
+				changes++
+			}
+		}
+		if changes != tst.changes {
+			t.Errorf("%s: got %d changed lines, want %d", tst.name, changes, tst.changes)
+		}
+		// The edit script itself takes about 1 MiB.
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+			t.Errorf("%s: allocated %d MiB", tst.name, alloc>>20)
+		}
+	}
+}
@@ -17,24 +17,273 @@ package errors
 
 import (
 	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
 )
 
 type myErr struct {
 	str string
 	err error
+
+	// If true, the error is caused by the input of the generator rather than by a bug in it.
+	user bool
+
+	// Stack trace of the goroutine creating the error, if it does not wrap another myErr.
+	stack []byte
+
+	// Position in the source of the element the error is about, if known.
+	pos Pos
 }
 
+// Error returns the messages of e and the errors it wraps, one per line.
+// If some of them are located in the source, the messages from the innermost located one
+// are instead printed on a single line following its position, as in
+//
+//	foo.proto:42:3: method GetThing: cannot find page size field "size" in Request
+//
+// which is the format understood by editors and other tools.
 func (e myErr) Error() string {
-	s := e.str
-	if e.err != nil {
-		s = s + "\n  " + e.err.Error()
+	at, ok := located(e)
+	if !ok {
+		s := e.str
+		if e.err != nil {
+			s = s + "\n  " + e.err.Error()
+		}
+		return s
+	}
+
+	s := at.pos.String() + ": " + at.str
+	for err := at.err; err != nil; {
+		inner, ok := err.(myErr)
+		if !ok {
+			return s + ": " + err.Error()
+		}
+		s += ": " + inner.str
+		err = inner.err
 	}
 	return s
 }
 
+// located returns the innermost error wrapped by err that has a position,
+// or false if there is none.
+func located(err error) (myErr, bool) {
+	var at myErr
+	var ok bool
+	for {
+		e, isMine := err.(myErr)
+		if !isMine {
+			return at, ok
+		}
+		if e.pos.File != "" {
+			at, ok = e, true
+		}
+		err = e.err
+	}
+}
+
+// Pos is a position in a source file. Line and Col start at 1.
+type Pos struct {
+	File      string
+	Line, Col int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// E returns an error described by s and a, wrapping cause, which may be nil.
 func E(cause error, s string, a ...interface{}) error {
-	return myErr{
-		str: fmt.Sprintf(s, a...),
-		err: cause,
+	return newErr(cause, false, s, a...)
+}
+
+// User is like E, but marks the error as caused by the user:
+// the protos, options or configuration files given to the generator
+// are invalid or describe something the generator does not support.
+// Errors wrapping a user error are user errors too.
+func User(cause error, s string, a ...interface{}) error {
+	return newErr(cause, true, s, a...)
+}
+
+// At is like E, but the error is about the element at pos in the source.
+// If pos is the zero Pos, the position is unknown and At is the same as E.
+func At(pos Pos, cause error, s string, a ...interface{}) error {
+	e := newErr(cause, false, s, a...).(myErr)
+	e.pos = pos
+	return e
+}
+
+func newErr(cause error, user bool, s string, a ...interface{}) error {
+	e := myErr{
+		str:  fmt.Sprintf(s, a...),
+		err:  cause,
+		user: user,
+	}
+	if _, ok := cause.(myErr); !ok {
+		e.stack = debug.Stack()
+	}
+	return e
+}
+
+// IsUser reports whether err is a user error, or wraps one.
+// An error returned by List.Err is a user error if all the errors in the list are.
+func IsUser(err error) bool {
+	if m, ok := err.(multiErr); ok {
+		for _, e := range m.errs {
+			if !IsUser(e) {
+				return false
+			}
+		}
+		return true
+	}
+	for err != nil {
+		e, ok := err.(myErr)
+		if !ok {
+			return false
+		}
+		if e.user {
+			return true
+		}
+		err = e.err
+	}
+	return false
+}
+
+// Stack returns the stack trace of the goroutine that created the innermost error created by this package
+// wrapped by err, or nil if err was not created by this package.
+// For an error returned by List.Err, it is the stack of the first error that is not a user error.
+func Stack(err error) []byte {
+	if m, ok := err.(multiErr); ok {
+		for _, e := range m.errs {
+			if !IsUser(e) {
+				return Stack(e)
+			}
+		}
+		return nil
+	}
+
+	var stack []byte
+	for {
+		e, ok := err.(myErr)
+		if !ok {
+			return stack
+		}
+		if e.stack != nil {
+			stack = e.stack
+		}
+		err = e.err
+	}
+}
+
+// List collects errors, so that all the problems found are reported at once.
+// The zero value is an empty list.
+type List struct {
+	errs []error
+}
+
+// Add adds err to l. A nil err is ignored.
+// If err is or wraps an error returned by List.Err, each error of that list is added separately,
+// wrapped like err.
+func (l *List) Add(err error) {
+	l.errs = append(l.errs, flatten(err)...)
+}
+
+// Len returns the number of errors added to l.
+func (l *List) Len() int {
+	return len(l.errs)
+}
+
+// Err returns nil if l is empty, the error if it has only one, or else an error listing the errors
+// without duplicates, sorted by position in the source, then by message. If max is positive, at most max errors are listed,
+// followed by the number of errors left out.
+func (l *List) Err(max int) error {
+	seen := map[string]bool{}
+	var errs []error
+	for _, e := range l.errs {
+		if s := e.Error(); !seen[s] {
+			seen[s] = true
+			errs = append(errs, e)
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		pi, iok := located(errs[i])
+		pj, jok := located(errs[j])
+		switch {
+		case iok != jok:
+			return iok
+		case iok && pi.pos != pj.pos:
+			a, b := pi.pos, pj.pos
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Col < b.Col
+		}
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(errs) == 1:
+		return errs[0]
+	}
+	m := multiErr{errs: errs}
+	if max > 0 && len(errs) > max {
+		m.omitted = len(errs) - max
+	}
+	return m
+}
+
+// multiErr is the error returned by List.Err for several errors.
+type multiErr struct {
+	errs []error
+
+	// Number of errors at the end of errs left out of the message.
+	omitted int
+}
+
+func (m multiErr) Error() string {
+	shown := m.errs[:len(m.errs)-m.omitted]
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d errors:", len(m.errs))
+	for _, e := range shown {
+		sb.WriteString("\n")
+		sb.WriteString(e.Error())
+	}
+	if m.omitted > 0 {
+		fmt.Fprintf(&sb, "\n(and %d more errors)", m.omitted)
+	}
+	return sb.String()
+}
+
+// flatten returns the errors listed by err, each wrapped the way err wraps the list.
+// If err does not wrap a list, it returns err alone.
+func flatten(err error) []error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case multiErr:
+		var errs []error
+		for _, x := range e.errs {
+			errs = append(errs, flatten(x)...)
+		}
+		return errs
+	case myErr:
+		inner := flatten(e.err)
+		if len(inner) <= 1 {
+			return []error{e}
+		}
+		var errs []error
+		for _, x := range inner {
+			w := e
+			w.err = x
+			errs = append(errs, w)
+		}
+		return errs
+	default:
+		return []error{err}
 	}
 }
@@ -0,0 +1,132 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestIsUser(t *testing.T) {
+	for _, tst := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"other package", io.EOF, false},
+		{"internal", E(nil, "bug"), false},
+		{"internal wrapping other package", E(io.EOF, "bug"), false},
+		{"user", User(nil, "bad input"), true},
+		{"user wrapping other package", User(io.EOF, "bad input"), true},
+		{"internal wrapping user", E(User(nil, "bad input"), "method: Foo"), true},
+		{"user wrapping internal", User(E(nil, "bug"), "bad input"), true},
+	} {
+		if got := IsUser(tst.err); got != tst.want {
+			t.Errorf("%s: IsUser(%v) = %t, want %t", tst.name, tst.err, got, tst.want)
+		}
+	}
+}
+
+func newInnermost() error {
+	return E(nil, "bug")
+}
+
+func TestStack(t *testing.T) {
+	if got := Stack(io.EOF); got != nil {
+		t.Errorf("Stack(io.EOF) = %q, want nil", got)
+	}
+
+	err := E(E(newInnermost(), "method: Foo"), "service: Bar")
+	if got := Stack(err); !bytes.Contains(got, []byte("newInnermost")) {
+		t.Errorf("Stack(%v) = %s, want the stack of the innermost error", err, got)
+	}
+}
+
+func TestList(t *testing.T) {
+	var l List
+	if err := l.Err(0); err != nil {
+		t.Errorf("empty list: Err(0) = %v, want nil", err)
+	}
+
+	l.Add(nil)
+	l.Add(User(nil, "b"))
+	if got, want := l.Err(0).Error(), "b"; got != want {
+		t.Errorf("one error: got %q, want %q", got, want)
+	}
+
+	var inner List
+	inner.Add(User(nil, "d"))
+	inner.Add(User(nil, "c"))
+	l.Add(E(inner.Err(0), "wrapped"))
+	l.Add(User(nil, "b"))
+	l.Add(User(nil, "a"))
+
+	err := l.Err(0)
+	if got, want := err.Error(), "4 errors:\na\nb\nwrapped\n  c\nwrapped\n  d"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !IsUser(err) {
+		t.Errorf("IsUser(%q) = false, want true", err)
+	}
+
+	if got, want := l.Err(2).Error(), "4 errors:\na\nb\n(and 2 more errors)"; got != want {
+		t.Errorf("Err(2): got %q, want %q", got, want)
+	}
+
+	l.Add(newInnermost())
+	if err := l.Err(0); IsUser(err) {
+		t.Errorf("IsUser(%q) = true, want false", err)
+	} else if got := Stack(err); !bytes.Contains(got, []byte("newInnermost")) {
+		t.Errorf("Stack(%v) = %s, want the stack of the internal error", err, got)
+	}
+}
+
+func TestAt(t *testing.T) {
+	pos := Pos{File: "foo.proto", Line: 42, Col: 3}
+	for _, tst := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"unknown position", At(Pos{}, User(nil, "bad"), "method GetThing"), "method GetThing\n  bad"},
+		{"located", At(pos, User(nil, "bad"), "method GetThing"), "foo.proto:42:3: method GetThing: bad"},
+		{"wrapped", E(At(pos, User(io.EOF, "bad"), "method GetThing"), "service Foo"), "foo.proto:42:3: method GetThing: bad: EOF"},
+		{
+			"innermost position",
+			At(Pos{File: "foo.proto", Line: 10, Col: 1}, At(pos, nil, "field size"), "method GetThing"),
+			"foo.proto:42:3: field size",
+		},
+	} {
+		if got := tst.err.Error(); got != tst.want {
+			t.Errorf("%s: got %q, want %q", tst.name, got, tst.want)
+		}
+	}
+
+	if !IsUser(At(pos, User(nil, "bad"), "method GetThing")) {
+		t.Errorf("At must keep the user errors it wraps")
+	}
+
+	var l List
+	l.Add(User(nil, "a"))
+	l.Add(At(Pos{File: "foo.proto", Line: 42, Col: 5}, nil, "c"))
+	l.Add(At(Pos{File: "bar.proto", Line: 50, Col: 1}, nil, "d"))
+	l.Add(At(pos, nil, "b"))
+	want := "4 errors:\nbar.proto:50:1: d\nfoo.proto:42:3: b\nfoo.proto:42:5: c\na"
+	if got := l.Err(0).Error(); got != want {
+		t.Errorf("List.Err: got %q, want %q", got, want)
+	}
+}
@@ -15,11 +15,14 @@
 package gengapic
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/genproto/googleapis/rpc/code"
@@ -38,14 +41,14 @@ func (g *generator) clientOptions(serv *descriptor.ServiceDescriptorProto, servN
 		p("}")
 		p("")
 
-		g.imports[pbinfo.ImportSpec{"gax", "github.com/googleapis/gax-go"}] = true
+		g.imports[pbinfo.ImportSpec{Name: "gax", Path: "github.com/googleapis/gax-go"}] = true
 	}
 
 	// defaultClientOptions
 	{
 		eHost, err := proto.GetExtension(serv.Options, annotations.E_DefaultHost)
 		if err != nil {
-			return errors.E(err, "cannot read default host")
+			return errors.At(g.pos(serv), errors.User(err, "cannot read default host"), "service %s", serv.GetName())
 		}
 
 		p("func default%sClientOptions() []option.ClientOption {", servName)
@@ -68,8 +71,16 @@ func (g *generator) clientOptions(serv *descriptor.ServiceDescriptorProto, servN
 
 		var defaultRetry []string
 		var overrideRetry []methodCode
+		var confMethods []*descriptor.MethodDescriptorProto
 
+		servFullName := g.fullyQualifiedName(serv)
 		for _, m := range serv.GetMethod() {
+			if g.grpcConf.methodConfig(servFullName, m.GetName()) != nil {
+				// Service config takes precedence over annotations.
+				confMethods = append(confMethods, m)
+				continue
+			}
+
 			if m.GetOptions() == nil {
 				// Some methods are not annotated, this is not an error.
 				continue
@@ -100,7 +111,6 @@ func (g *generator) clientOptions(serv *descriptor.ServiceDescriptorProto, servN
 			}
 		}
 
-		// TODO(pongad): read retry params from somewhere
 		p("func default%[1]sCallOptions() *%[1]sCallOptions {", servName)
 
 		if len(defaultRetry) > 0 || len(overrideRetry) > 0 {
@@ -136,17 +146,15 @@ func (g *generator) clientOptions(serv *descriptor.ServiceDescriptorProto, servN
 			p("  gax.WithRetry(func() gax.Retryer {")
 			p("    return gax.OnCodes([]codes.Code{")
 			for _, c := range retry.codes {
-				if c == code.Code_CANCELLED {
-					// Go uses one 'l' spelling.
-					p("codes.Canceled,")
-				} else {
-					p("codes.%s,", snakeToCamel(c.String()))
-				}
+				p("codes.%s,", grpcCodeName(c))
 			}
 			p("    }, backoff)")
 			p("  }),")
 			p("},")
 		}
+		for _, m := range confMethods {
+			g.configuredCallOptions(m, g.grpcConf.methodConfig(servFullName, m.GetName()))
+		}
 		p("  }")
 		p("}")
 		p("")
@@ -155,6 +163,68 @@ func (g *generator) clientOptions(serv *descriptor.ServiceDescriptorProto, servN
 	return nil
 }
 
+// configuredCallOptions prints the call options of method m, as set in the gRPC service config.
+// The timeout is applied by the method itself, see callTimeout.
+func (g *generator) configuredCallOptions(m *descriptor.MethodDescriptorProto, mc *methodConfig) {
+	p := g.printf
+
+	p("%s: []gax.CallOption{", m.GetName())
+	if rp := mc.retry; rp != nil && len(rp.codes) > 0 {
+		p("  gax.WithRetry(func() gax.Retryer {")
+		p("    return gax.OnCodes([]codes.Code{")
+		for _, c := range rp.codes {
+			p("codes.%s,", grpcCodeName(c))
+		}
+		p("    }, gax.Backoff{")
+		p("      Initial: %s,", durationExpr(rp.initialBackoff))
+		p("      Max: %s,", durationExpr(rp.maxBackoff))
+		p("      Multiplier: %v,", rp.backoffMultiplier)
+		p("    })")
+		p("  }),")
+		g.imports[pbinfo.ImportSpec{Path: "time"}] = true
+		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc/codes"}] = true
+	}
+	p("},")
+}
+
+// durationExpr returns the Go expression for d, in milliseconds if d is a whole number of them.
+func durationExpr(d time.Duration) string {
+	for _, u := range []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Millisecond, "Millisecond"},
+		{time.Microsecond, "Microsecond"},
+	} {
+		if d%u.d == 0 {
+			return fmt.Sprintf("%d * time.%s", d/u.d, u.name)
+		}
+	}
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
+
+// grpcCodeName reports the name of the constant in google.golang.org/grpc/codes for c.
+func grpcCodeName(c code.Code) string {
+	if c == code.Code_CANCELLED {
+		// Go uses one 'l' spelling.
+		return "Canceled"
+	}
+	return naming.CamelCase(strings.ToLower(c.String()))
+}
+
+// clientConstructors reports the names of the functions creating a client of the service,
+// one for each transport.
+func (g *generator) clientConstructors(servName string) []string {
+	var ctors []string
+	if g.opts.hasTransport(grpcTransport) {
+		ctors = append(ctors, "New"+servName+"Client")
+	}
+	if g.opts.hasTransport(restTransport) {
+		ctors = append(ctors, "New"+servName+"RESTClient")
+	}
+	return ctors
+}
+
 func (g *generator) clientInit(serv *descriptor.ServiceDescriptorProto, servName string) error {
 	p := g.printf
 
@@ -166,7 +236,7 @@ func (g *generator) clientInit(serv *descriptor.ServiceDescriptorProto, servName
 		}
 	}
 
-	imp, err := g.descInfo.ImportSpec(serv)
+	imp, err := g.importSpec(serv)
 	if err != nil {
 		return err
 	}
@@ -179,11 +249,14 @@ func (g *generator) clientInit(serv *descriptor.ServiceDescriptorProto, servName
 		p("type %sClient struct {", servName)
 
 		p("// The connection to the service.")
+		if g.opts.hasTransport(restTransport) {
+			p("// It is nil if the client uses REST.")
+		}
 		p("conn *grpc.ClientConn")
 		p("")
 
 		p("// The gRPC API client.")
-		p("%s %s.%sClient", grpcClientField(servName), imp.Name, serv.GetName())
+		p("%s %s.%sClient", g.grpcClientField(servName), imp.Name, serv.GetName())
 		p("")
 
 		if hasLRO {
@@ -210,8 +283,8 @@ func (g *generator) clientInit(serv *descriptor.ServiceDescriptorProto, servName
 	}
 
 	// Client constructor
-	{
-		clientName := camelToSnake(serv.GetName())
+	if g.opts.hasTransport(grpcTransport) {
+		clientName := naming.Snake(serv.GetName())
 		clientName = strings.Replace(clientName, "_", " ", -1)
 
 		p("// New%sClient creates a new %s client.", servName, clientName)
@@ -226,7 +299,7 @@ func (g *generator) clientInit(serv *descriptor.ServiceDescriptorProto, servName
 		p("    conn:        conn,")
 		p("    CallOptions: default%sCallOptions(),", servName)
 		p("")
-		p("    %s: %s.New%sClient(conn),", grpcClientField(servName), imp.Name, serv.GetName())
+		p("    %s: %s.New%sClient(conn),", g.grpcClientField(servName), imp.Name, serv.GetName())
 		p("  }")
 		p("  c.setGoogleClientInfo()")
 		p("")
@@ -249,13 +322,17 @@ func (g *generator) clientInit(serv *descriptor.ServiceDescriptorProto, servName
 		p("")
 
 		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/api/transport"}] = true
-		g.imports[pbinfo.ImportSpec{Path: "golang.org/x/net/context"}] = true
 	}
+	// The methods of the client take a context, whatever the transport.
+	g.imports[pbinfo.ImportSpec{Path: "golang.org/x/net/context"}] = true
 
 	// Connection()
 	{
 		p("// Connection returns the client's connection to the API service.")
-		p("func (c *%sClient) Connection() *grpc.ClientConn {", servName)
+		if g.opts.hasTransport(restTransport) {
+			p("// It is nil for clients created with New%sRESTClient.", servName)
+		}
+		g.clientMethod(servName, "Connection() *grpc.ClientConn")
 		p("  return c.conn")
 		p("}")
 		p("")
@@ -265,7 +342,12 @@ func (g *generator) clientInit(serv *descriptor.ServiceDescriptorProto, servName
 	{
 		p("// Close closes the connection to the API service. The user should invoke this when")
 		p("// the client is no longer required.")
-		p("func (c *%sClient) Close() error {", servName)
+		g.clientMethod(servName, "Close() error")
+		if g.opts.hasTransport(restTransport) {
+			p("  if c.conn == nil {")
+			p("    return nil")
+			p("  }")
+		}
 		p("  return c.conn.Close()")
 		p("}")
 		p("")
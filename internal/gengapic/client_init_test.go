@@ -18,6 +18,7 @@ import (
 	"flag"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
@@ -52,6 +53,7 @@ func TestClientOpt(t *testing.T) {
 	g.imports = map[pbinfo.ImportSpec]bool{}
 
 	serv := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("Foo"),
 		Method: []*descriptor.MethodDescriptorProto{
 			{Name: proto.String("Zip"), Options: &descriptor.MethodOptions{}},
 			{Name: proto.String("Zap"), Options: &descriptor.MethodOptions{}},
@@ -77,13 +79,32 @@ func TestClientOpt(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	conf, err := parseGRPCConfig(strings.NewReader(`{
+		"methodConfig": [{
+			"name": [{"service": "Foo", "method": "Zap"}, {"service": "Foo", "method": "Smack"}],
+			"timeout": "60s",
+			"retryPolicy": {
+				"initialBackoff": "0.0025s",
+				"maxBackoff": "30s",
+				"backoffMultiplier": 2.5,
+				"retryableStatusCodes": ["UNAVAILABLE", "CANCELLED"]
+			}
+		}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	for _, tst := range []struct {
 		tstName, servName string
+		conf              grpcConfig
 	}{
 		{tstName: "foo_opt", servName: "Foo"},
 		{tstName: "empty_opt", servName: ""},
+		{tstName: "foo_opt_conf", servName: "Foo", conf: conf},
 	} {
 		g.reset()
+		g.grpcConf = tst.conf
 		if err := g.clientOptions(serv, tst.servName); err != nil {
 			t.Error(err)
 			continue
@@ -15,6 +15,9 @@
 package gengapic
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/googleapis/gapic-generator-go/internal/errors"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
@@ -24,38 +27,53 @@ func (g *generator) genExampleFile(serv *descriptor.ServiceDescriptorProto, pkgN
 	servName := pbinfo.ReduceServName(*serv.Name, pkgName)
 	p := g.printf
 
-	p("func ExampleNew%sClient() {", servName)
-	g.exampleInitClient(pkgName, servName)
-	p("  // TODO: Use client.")
-	p("  _ = c")
-	p("}")
-	p("")
+	for _, ctor := range g.clientConstructors(servName) {
+		p("func Example%s() {", ctor)
+		g.exampleNewClient(pkgName, ctor)
+		p("  // TODO: Use client.")
+		p("  _ = c")
+		p("}")
+		p("")
+	}
 	g.imports[pbinfo.ImportSpec{Path: "golang.org/x/net/context"}] = true
 
+	// The signatures that cannot be flattened are reported by the client; they have no example.
+	sigs, _ := g.serviceSignatures(serv)
+
 	for _, m := range serv.Method {
-		if err := g.exampleMethod(pkgName, servName, m); err != nil {
+		if err := g.exampleMethod(pkgName, servName, serv, m); err != nil {
 			return err
 		}
+		if err := g.examplePages(pkgName, servName, serv, m); err != nil {
+			return err
+		}
+		if m.GetOutputType() == lroType {
+			g.exampleLROControl(pkgName, servName, m)
+		}
+		for _, sig := range sigs[m] {
+			if err := g.exampleFlattened(pkgName, servName, serv, m, sig); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
 func (g *generator) exampleInitClient(pkgName, servName string) {
+	g.exampleNewClient(pkgName, g.clientConstructors(servName)[0])
+}
+
+func (g *generator) exampleNewClient(pkgName, ctor string) {
 	p := g.printf
 
 	p("ctx := context.Background()")
-	p("c, err := %s.New%sClient(ctx)", pkgName, servName)
+	p("c, err := %s.%s(ctx)", pkgName, ctor)
 	p("if err != nil {")
 	p("  // TODO: Handle error.")
 	p("}")
 }
 
-func (g *generator) exampleMethod(pkgName, servName string, m *descriptor.MethodDescriptorProto) error {
-	if m.GetClientStreaming() != m.GetServerStreaming() {
-		// TODO(pongad): implement this correctly.
-		return nil
-	}
-
+func (g *generator) exampleMethod(pkgName, servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
 	p := g.printf
 
 	inType := g.descInfo.Type[m.GetInputType()]
@@ -63,51 +81,154 @@ func (g *generator) exampleMethod(pkgName, servName string, m *descriptor.Method
 		return errors.E(nil, "cannot find type %q, malformed descriptor?", m.GetInputType())
 	}
 
-	inSpec, err := g.descInfo.ImportSpec(inType)
+	inSpec, err := g.importSpec(inType)
 	if err != nil {
 		return err
 	}
 
 	g.imports[inSpec] = true
 
-	p("func Example%sClient_%s() {", servName, m.GetName())
+	p("func Example%sClient_%s() {", servName, g.clientMethodName(m))
 	g.exampleInitClient(pkgName, servName)
 
-	if !m.GetClientStreaming() && !m.GetServerStreaming() {
+	if !m.GetClientStreaming() {
 		p("")
 		p("req := &%s.%s{", inSpec.Name, inType.GetName())
 		p("  // TODO: Fill request struct fields.")
 		p("}")
 	}
 
-	if pf, err := g.pagingField(m); err != nil {
+	if err := g.exampleCall(serv, m, fmt.Sprintf("c.%s(ctx, req)", g.clientMethodName(m))); err != nil {
+		return err
+	}
+
+	p("}")
+	p("")
+	return nil
+}
+
+// examplePages generates the example of the method iterating over pages of results of m,
+// if m is a paging method.
+func (g *generator) examplePages(pkgName, servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
+	if pi, err := g.pagingInfoOf(serv, m); err != nil || pi == nil {
+		return err
+	}
+
+	p := g.printf
+
+	inType := g.descInfo.Type[m.GetInputType()]
+	inSpec, err := g.importSpec(inType)
+	if err != nil {
 		return err
-	} else if pf != nil {
-		g.examplePagingCall(m)
-	} else if *m.OutputType == lroType {
-		g.exampleLROCall(m)
-	} else if *m.OutputType == emptyType {
-		g.exampleEmptyCall(m)
-	} else if m.GetClientStreaming() && m.GetServerStreaming() {
-		g.exampleBidiCall(m, inType, inSpec)
-	} else {
-		g.exampleUnaryCall(m)
 	}
+	g.imports[inSpec] = true
 
+	p("func Example%sClient_%s() {", servName, g.pagesMethodName(m))
+	g.exampleInitClient(pkgName, servName)
+	p("")
+	p("req := &%s.%s{", inSpec.Name, inType.GetName())
+	p("  // TODO: Fill request struct fields.")
+	p("}")
+	p("it := c.%s(ctx, req)", g.pagesMethodName(m))
+	p("for {")
+	p("  page, err := it.Next()")
+	p("  if err == iterator.Done {")
+	p("    break")
+	p("  }")
+	p("  if err != nil {")
+	p("    // TODO: Handle error.")
+	p("  }")
+	p("  // TODO: Use page.Response and page.Items.")
+	p("  _ = page")
+	p("}")
 	p("}")
 	p("")
+
+	g.imports[pbinfo.ImportSpec{Path: "google.golang.org/api/iterator"}] = true
 	return nil
 }
 
-func (g *generator) exampleLROCall(m *descriptor.MethodDescriptorProto) {
+// exampleFlattened generates the example of flattened method sig of m.
+func (g *generator) exampleFlattened(pkgName, servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, sig signature) error {
 	p := g.printf
 
-	p("op, err := c.%s(ctx, req)", *m.Name)
+	p("func Example%sClient_%s() {", servName, sig.name)
+	g.exampleInitClient(pkgName, servName)
+	p("")
+	p("// TODO: Fill arguments.")
+
+	var args strings.Builder
+	args.WriteString("ctx")
+	for _, prm := range sig.params {
+		p("var %s %s", prm.name, prm.typ)
+		for _, imp := range prm.imports {
+			g.imports[imp] = true
+		}
+		fmt.Fprintf(&args, ", %s", prm.name)
+	}
+	p("")
+
+	if err := g.exampleCall(serv, m, fmt.Sprintf("c.%s(%s)", sig.name, args.String())); err != nil {
+		return err
+	}
+
+	p("}")
+	p("")
+	return nil
+}
+
+// exampleCall prints how to call and use the result of the client method generated for m of serv,
+// using the call expression call.
+func (g *generator) exampleCall(serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, call string) error {
+	if pi, err := g.pagingInfoOf(serv, m); err != nil {
+		return err
+	} else if pi != nil {
+		g.examplePagingCall(call)
+	} else if *m.OutputType == lroType {
+		respEmpty, err := g.lroResponseIsEmpty(serv, m)
+		if err != nil {
+			return err
+		}
+		g.exampleLROCall(call, respEmpty)
+	} else if *m.OutputType == emptyType {
+		g.exampleEmptyCall(call)
+	} else if m.GetClientStreaming() {
+		inType := g.descInfo.Type[m.GetInputType()]
+		inSpec, err := g.importSpec(inType)
+		if err != nil {
+			return err
+		}
+		if m.GetServerStreaming() {
+			g.exampleBidiCall(m, inType, inSpec)
+		} else {
+			g.exampleClientStreamCall(m, inType, inSpec)
+		}
+	} else if m.GetServerStreaming() {
+		g.exampleServerStreamCall(call)
+	} else {
+		g.exampleUnaryCall(call)
+	}
+	return nil
+}
+
+// exampleLROCall prints how to start an operation and wait for it.
+// If respEmpty, the operation has no response to use.
+func (g *generator) exampleLROCall(call string, respEmpty bool) {
+	p := g.printf
+
+	p("op, err := %s", call)
 	p("if err != nil {")
 	p("  // TODO: Handle error.")
 	p("}")
 	p("")
 
+	if respEmpty {
+		p("err = op.Wait(ctx)")
+		p("if err != nil {")
+		p("  // TODO: Handle error.")
+		p("}")
+		return
+	}
 	p("resp, err := op.Wait(ctx)")
 	p("if err != nil {")
 	p("  // TODO: Handle error.")
@@ -116,10 +237,29 @@ func (g *generator) exampleLROCall(m *descriptor.MethodDescriptorProto) {
 	p("_ = resp")
 }
 
-func (g *generator) exampleUnaryCall(m *descriptor.MethodDescriptorProto) {
+// exampleLROControl generates the examples of cancelling and deleting an operation of LRO method m.
+func (g *generator) exampleLROControl(pkgName, servName string, m *descriptor.MethodDescriptorProto) {
 	p := g.printf
 
-	p("resp, err := c.%s(ctx, req)", *m.Name)
+	opType := g.lroTypeName(m)
+	for _, meth := range []string{"Cancel", "Delete"} {
+		p("func Example%s_%s() {", opType, meth)
+		g.exampleInitClient(pkgName, servName)
+		p("")
+		p("// TODO: Use the name of an operation started by %s.", g.clientMethodName(m))
+		p("op := c.%s(\"name\")", opType)
+		p("if err := op.%s(ctx); err != nil {", meth)
+		p("  // TODO: Handle error.")
+		p("}")
+		p("}")
+		p("")
+	}
+}
+
+func (g *generator) exampleUnaryCall(call string) {
+	p := g.printf
+
+	p("resp, err := %s", call)
 	p("if err != nil {")
 	p("  // TODO: Handle error.")
 	p("}")
@@ -127,19 +267,19 @@ func (g *generator) exampleUnaryCall(m *descriptor.MethodDescriptorProto) {
 	p("_ = resp")
 }
 
-func (g *generator) exampleEmptyCall(m *descriptor.MethodDescriptorProto) {
+func (g *generator) exampleEmptyCall(call string) {
 	p := g.printf
 
-	p("err = c.%s(ctx, req)", *m.Name)
+	p("err = %s", call)
 	p("if err != nil {")
 	p("  // TODO: Handle error.")
 	p("}")
 }
 
-func (g *generator) examplePagingCall(m *descriptor.MethodDescriptorProto) {
+func (g *generator) examplePagingCall(call string) {
 	p := g.printf
 
-	p("it := c.%s(ctx, req)", *m.Name)
+	p("it := %s", call)
 	p("for {")
 	p("  resp, err := it.Next()")
 	p("  if err == iterator.Done {")
@@ -158,7 +298,7 @@ func (g *generator) examplePagingCall(m *descriptor.MethodDescriptorProto) {
 func (g *generator) exampleBidiCall(m *descriptor.MethodDescriptorProto, inType pbinfo.ProtoType, inSpec pbinfo.ImportSpec) {
 	p := g.printf
 
-	p("stream, err := c.%s(ctx)", m.GetName())
+	p("stream, err := c.%s(ctx)", g.clientMethodName(m))
 	p("if err != nil {")
 	p("  // TODO: Handle error.")
 	p("}")
@@ -175,6 +315,49 @@ func (g *generator) exampleBidiCall(m *descriptor.MethodDescriptorProto, inType
 	p("  stream.CloseSend()")
 	p("}()")
 
+	g.exampleRecv()
+}
+
+func (g *generator) exampleClientStreamCall(m *descriptor.MethodDescriptorProto, inType pbinfo.ProtoType, inSpec pbinfo.ImportSpec) {
+	p := g.printf
+
+	p("stream, err := c.%s(ctx)", g.clientMethodName(m))
+	p("if err != nil {")
+	p("  // TODO: Handle error.")
+	p("}")
+
+	p("reqs := []*%s.%s{", inSpec.Name, inType.GetName())
+	p("  // TODO: Create requests.")
+	p("}")
+	p("for _, req := range reqs {")
+	p("  if err := stream.Send(req); err != nil {")
+	p("    // TODO: Handle error.")
+	p("  }")
+	p("}")
+
+	p("resp, err := stream.CloseAndRecv()")
+	p("if err != nil {")
+	p("  // TODO: Handle error.")
+	p("}")
+	p("// TODO: Use resp.")
+	p("_ = resp")
+}
+
+func (g *generator) exampleServerStreamCall(call string) {
+	p := g.printf
+
+	p("stream, err := %s", call)
+	p("if err != nil {")
+	p("  // TODO: Handle error.")
+	p("}")
+
+	g.exampleRecv()
+}
+
+// exampleRecv prints how to receive the responses of a server stream.
+func (g *generator) exampleRecv() {
+	p := g.printf
+
 	p("for {")
 	p("  resp, err := stream.Recv()")
 	p("  if err == io.EOF {")
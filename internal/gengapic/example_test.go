@@ -21,6 +21,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
 )
 
 func TestExample(t *testing.T) {
@@ -86,6 +87,16 @@ func TestExample(t *testing.T) {
 		g.descInfo.ParentFile[typ] = file
 	}
 
+	lroOpts := func(resp string) *descriptor.MethodOptions {
+		opts := &descriptor.MethodOptions{}
+		if err := proto.SetExtension(opts, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+			Response: resp,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return opts
+	}
+
 	serv := &descriptor.ServiceDescriptorProto{
 		Name: proto.String("Foo"),
 		Method: []*descriptor.MethodDescriptorProto{
@@ -103,12 +114,31 @@ func TestExample(t *testing.T) {
 				Name:       proto.String("GetBigThing"),
 				InputType:  proto.String(".my.pkg.InputType"),
 				OutputType: proto.String(".google.longrunning.Operation"),
+				Options:    lroOpts("OutputType"),
+			},
+			{
+				Name:       proto.String("DeleteBigThing"),
+				InputType:  proto.String(".my.pkg.InputType"),
+				OutputType: proto.String(".google.longrunning.Operation"),
+				Options:    lroOpts("google.protobuf.Empty"),
 			},
 			{
 				Name:       proto.String("GetManyThings"),
 				InputType:  proto.String(".my.pkg.PageInputType"),
 				OutputType: proto.String(".my.pkg.PageOutputType"),
 			},
+			{
+				Name:            proto.String("ServerThings"),
+				InputType:       proto.String(".my.pkg.InputType"),
+				OutputType:      proto.String(".my.pkg.OutputType"),
+				ServerStreaming: proto.Bool(true),
+			},
+			{
+				Name:            proto.String("ClientThings"),
+				InputType:       proto.String(".my.pkg.InputType"),
+				OutputType:      proto.String(".my.pkg.OutputType"),
+				ClientStreaming: proto.Bool(true),
+			},
 			{
 				Name:            proto.String("BidiThings"),
 				InputType:       proto.String(".my.pkg.InputType"),
@@ -118,6 +148,8 @@ func TestExample(t *testing.T) {
 			},
 		},
 	}
+	g.descInfo.ParentFile[serv] = file
+
 	for _, tst := range []struct {
 		tstName, pkgName string
 	}{
@@ -125,7 +157,9 @@ func TestExample(t *testing.T) {
 		{tstName: "foo_example", pkgName: "Bar"},
 	} {
 		g.reset()
-		g.genExampleFile(serv, tst.pkgName)
+		if err := g.genExampleFile(serv, tst.pkgName); err != nil {
+			t.Fatal(err)
+		}
 		diff(t, tst.tstName, g.pt.String(), filepath.Join("testdata", tst.tstName+".want"))
 	}
 }
@@ -0,0 +1,193 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"io"
+	"time"
+
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// gapicConfig contains the per-method settings from a GAPIC config file.
+type gapicConfig struct {
+	// Maps fully-qualified service name, then method name, to the settings of the method.
+	methods map[string]map[string]*gapicMethodConfig
+}
+
+// gapicMethodConfig contains the settings of a method from a GAPIC config file.
+type gapicMethodConfig struct {
+	// If nil, the paging of the method is not configured.
+	paging *pagingConfig
+
+	// If nil, the polling of the long-running operations of the method is not configured.
+	polling *pollingConfig
+}
+
+// pagingConfig declares the fields used to paginate a method,
+// in place of the ones found by the naming heuristic.
+type pagingConfig struct {
+	// If true, the method is not paginated, even if it looks like it is.
+	disabled bool
+
+	// Fields of the request. pageSizeField is empty if the page size cannot be set.
+	pageSizeField, tokenField string
+
+	// Fields of the response. resourcesField is the repeated field iterated over.
+	nextTokenField, resourcesField string
+}
+
+// pollingConfig contains the settings to poll a long-running operation until it completes.
+type pollingConfig struct {
+	// Initial and maximum delays between polls.
+	initialDelay, maxDelay time.Duration
+
+	// Factor by which the delay grows after each poll.
+	multiplier float64
+
+	// Time after which polling stops. Zero means no limit.
+	totalTimeout time.Duration
+}
+
+// defaultPolling is used for methods whose polling is not configured,
+// and for the settings missing from a configuration.
+var defaultPolling = pollingConfig{
+	initialDelay: 500 * time.Millisecond,
+	maxDelay:     time.Minute,
+	multiplier:   1.5,
+}
+
+// YAML representation of the GAPIC config.
+// We only read the parts relevant to the generated clients.
+type yamlGAPICConfig struct {
+	Interfaces []struct {
+		Name    string `yaml:"name"`
+		Methods []struct {
+			Name          string `yaml:"name"`
+			DisablePaging bool   `yaml:"disable_paging"`
+			PageStreaming *struct {
+				Request struct {
+					PageSizeField string `yaml:"page_size_field"`
+					TokenField    string `yaml:"token_field"`
+				} `yaml:"request"`
+				Response struct {
+					TokenField     string `yaml:"token_field"`
+					ResourcesField string `yaml:"resources_field"`
+				} `yaml:"response"`
+			} `yaml:"page_streaming"`
+			LongRunning *struct {
+				InitialPollDelayMillis int64   `yaml:"initial_poll_delay_millis"`
+				PollDelayMultiplier    float64 `yaml:"poll_delay_multiplier"`
+				MaxPollDelayMillis     int64   `yaml:"max_poll_delay_millis"`
+				TotalPollTimeoutMillis int64   `yaml:"total_poll_timeout_millis"`
+			} `yaml:"long_running"`
+		} `yaml:"methods"`
+	} `yaml:"interfaces"`
+}
+
+// parseGAPICConfig reads a GAPIC config in YAML format from r.
+func parseGAPICConfig(r io.Reader) (gapicConfig, error) {
+	var y yamlGAPICConfig
+	if err := yaml.NewDecoder(r).Decode(&y); err != nil {
+		return gapicConfig{}, errors.E(err, "cannot decode GAPIC config")
+	}
+
+	conf := gapicConfig{methods: map[string]map[string]*gapicMethodConfig{}}
+	for _, inf := range y.Interfaces {
+		if inf.Name == "" {
+			return gapicConfig{}, errors.E(nil, "interface needs name")
+		}
+		for _, ym := range inf.Methods {
+			if ym.Name == "" {
+				return gapicConfig{}, errors.E(nil, "interface %s: method needs name", inf.Name)
+			}
+
+			var mc gapicMethodConfig
+			switch ps := ym.PageStreaming; {
+			case ym.DisablePaging && ps != nil:
+				return gapicConfig{}, errors.E(nil, "method %s.%s: cannot both disable paging and set page_streaming", inf.Name, ym.Name)
+			case ym.DisablePaging:
+				mc.paging = &pagingConfig{disabled: true}
+			case ps != nil:
+				pc := pagingConfig{
+					pageSizeField:  ps.Request.PageSizeField,
+					tokenField:     ps.Request.TokenField,
+					nextTokenField: ps.Response.TokenField,
+					resourcesField: ps.Response.ResourcesField,
+				}
+				if pc.tokenField == "" || pc.nextTokenField == "" || pc.resourcesField == "" {
+					return gapicConfig{}, errors.E(nil, "method %s.%s: page_streaming needs request.token_field, response.token_field and response.resources_field", inf.Name, ym.Name)
+				}
+				mc.paging = &pc
+			}
+
+			if lr := ym.LongRunning; lr != nil {
+				pc := defaultPolling
+				if lr.InitialPollDelayMillis != 0 {
+					pc.initialDelay = time.Duration(lr.InitialPollDelayMillis) * time.Millisecond
+				}
+				if lr.MaxPollDelayMillis != 0 {
+					pc.maxDelay = time.Duration(lr.MaxPollDelayMillis) * time.Millisecond
+				}
+				if lr.PollDelayMultiplier != 0 {
+					pc.multiplier = lr.PollDelayMultiplier
+				}
+				pc.totalTimeout = time.Duration(lr.TotalPollTimeoutMillis) * time.Millisecond
+				switch {
+				case pc.initialDelay <= 0 || pc.totalTimeout < 0:
+					return gapicConfig{}, errors.E(nil, "method %s.%s: poll delays and timeout must be positive", inf.Name, ym.Name)
+				case pc.maxDelay < pc.initialDelay:
+					return gapicConfig{}, errors.E(nil, "method %s.%s: max_poll_delay_millis must not be less than initial_poll_delay_millis", inf.Name, ym.Name)
+				case pc.multiplier < 1:
+					return gapicConfig{}, errors.E(nil, "method %s.%s: poll_delay_multiplier must be at least 1, got %v", inf.Name, ym.Name, pc.multiplier)
+				}
+				mc.polling = &pc
+			}
+
+			if mc.paging == nil && mc.polling == nil {
+				continue
+			}
+			ms := conf.methods[inf.Name]
+			if ms == nil {
+				ms = map[string]*gapicMethodConfig{}
+				conf.methods[inf.Name] = ms
+			}
+			if _, dup := ms[ym.Name]; dup {
+				return gapicConfig{}, errors.E(nil, "duplicate config for method %s.%s", inf.Name, ym.Name)
+			}
+			ms[ym.Name] = &mc
+		}
+	}
+	return conf, nil
+}
+
+// pagingConfig returns the paging settings of the method, or nil if the method is not configured.
+// serv must be fully-qualified, without the leading dot.
+func (c gapicConfig) pagingConfig(serv, meth string) *pagingConfig {
+	if mc := c.methods[serv][meth]; mc != nil {
+		return mc.paging
+	}
+	return nil
+}
+
+// pollingConfig returns the LRO polling settings of the method, or defaultPolling if the method is not configured.
+// serv must be fully-qualified, without the leading dot.
+func (c gapicConfig) pollingConfig(serv, meth string) pollingConfig {
+	if mc := c.methods[serv][meth]; mc != nil && mc.polling != nil {
+		return *mc.polling
+	}
+	return defaultPolling
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseGAPICConfig(t *testing.T) {
+	conf, err := parseGAPICConfig(strings.NewReader(`
+type: com.google.api.codegen.ConfigProto
+interfaces:
+- name: my.pkg.Foo
+  methods:
+  - name: ListThings
+    page_streaming:
+      request:
+        page_size_field: max_results
+        token_field: page_token
+      response:
+        token_field: next_page_token
+        resources_field: things
+  - name: ListOthers
+    disable_paging: true
+  - name: GetThing
+    retry_codes_name: idempotent
+  - name: BuildThing
+    long_running:
+      initial_poll_delay_millis: 100
+      max_poll_delay_millis: 2000
+      total_poll_timeout_millis: 60000
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tst := range []struct {
+		serv, meth string
+		want       *pagingConfig
+	}{
+		{
+			serv: "my.pkg.Foo",
+			meth: "ListThings",
+			want: &pagingConfig{
+				pageSizeField:  "max_results",
+				tokenField:     "page_token",
+				nextTokenField: "next_page_token",
+				resourcesField: "things",
+			},
+		},
+		{
+			serv: "my.pkg.Foo",
+			meth: "ListOthers",
+			want: &pagingConfig{disabled: true},
+		},
+		{
+			serv: "my.pkg.Foo",
+			meth: "GetThing",
+		},
+		{
+			serv: "my.pkg.Bar",
+			meth: "ListThings",
+		},
+	} {
+		got := conf.pagingConfig(tst.serv, tst.meth)
+		if diff := cmp.Diff(got, tst.want, cmp.AllowUnexported(pagingConfig{})); diff != "" {
+			t.Errorf("pagingConfig(%q, %q): (-got,+want)\n%s", tst.serv, tst.meth, diff)
+		}
+	}
+
+	for _, tst := range []struct {
+		serv, meth string
+		want       pollingConfig
+	}{
+		{
+			serv: "my.pkg.Foo",
+			meth: "BuildThing",
+			want: pollingConfig{
+				initialDelay: 100 * time.Millisecond,
+				maxDelay:     2 * time.Second,
+				multiplier:   1.5,
+				totalTimeout: time.Minute,
+			},
+		},
+		{
+			serv: "my.pkg.Foo",
+			meth: "ListThings",
+			want: defaultPolling,
+		},
+		{
+			serv: "my.pkg.Bar",
+			meth: "BuildThing",
+			want: defaultPolling,
+		},
+	} {
+		got := conf.pollingConfig(tst.serv, tst.meth)
+		if diff := cmp.Diff(got, tst.want, cmp.AllowUnexported(pollingConfig{})); diff != "" {
+			t.Errorf("pollingConfig(%q, %q): (-got,+want)\n%s", tst.serv, tst.meth, diff)
+		}
+	}
+}
+
+func TestParseGAPICConfigError(t *testing.T) {
+	for _, in := range []string{
+		`interfaces: {}`,
+		`interfaces: [{methods: [{name: List, disable_paging: true}]}]`,
+		`interfaces: [{name: Foo, methods: [{disable_paging: true}]}]`,
+		`interfaces: [{name: Foo, methods: [{name: List, disable_paging: true, page_streaming: {}}]}]`,
+		`interfaces: [{name: Foo, methods: [{name: List, page_streaming: {request: {token_field: t}}}]}]`,
+		`interfaces: [{name: Foo, methods: [{name: List, disable_paging: true}, {name: List, disable_paging: true}]}]`,
+		`interfaces: [{name: Foo, methods: [{name: Build, long_running: {initial_poll_delay_millis: -1}}]}]`,
+		`interfaces: [{name: Foo, methods: [{name: Build, long_running: {total_poll_timeout_millis: -1}}]}]`,
+		`interfaces: [{name: Foo, methods: [{name: Build, long_running: {initial_poll_delay_millis: 2000, max_poll_delay_millis: 1000}}]}]`,
+		`interfaces: [{name: Foo, methods: [{name: Build, long_running: {poll_delay_multiplier: 0.5}}]}]`,
+	} {
+		if _, err := parseGAPICConfig(strings.NewReader(in)); err == nil {
+			t.Errorf("parseGAPICConfig(%q): expected error", in)
+		}
+	}
+}
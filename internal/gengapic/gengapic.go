@@ -16,8 +16,12 @@ package gengapic
 
 import (
 	"fmt"
+	"go/token"
+	"log"
+	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -28,6 +32,7 @@ import (
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
 	"github.com/googleapis/gapic-generator-go/internal/errors"
 	"github.com/googleapis/gapic-generator-go/internal/license"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
 	"github.com/googleapis/gapic-generator-go/internal/printer"
 	"google.golang.org/genproto/googleapis/api/annotations"
@@ -40,27 +45,49 @@ const (
 )
 
 func Gen(genReq *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse, error) {
-	var pkgPath, pkgName string
-	if genReq.Parameter == nil {
-		return nil, errors.E(nil, "need parameter in format: client/import/path;packageName")
-	}
-	if p := strings.IndexByte(*genReq.Parameter, ';'); p < 0 {
-		return nil, errors.E(nil, "need parameter in format: client/import/path;packageName")
-	} else {
-		pkgPath = (*genReq.Parameter)[:p]
-		pkgName = (*genReq.Parameter)[p+1:]
+	opts, err := parseOptions(genReq.Parameter)
+	if err != nil {
+		return nil, err
 	}
+	pkgPath, pkgName := opts.pkgPath, opts.pkgName
 	outDir := filepath.FromSlash(pkgPath)
 
 	var g generator
+	g.opts = *opts
 	g.init(genReq.ProtoFile)
 
+	if opts.grpcConfPath != "" {
+		f, err := os.Open(opts.grpcConfPath)
+		if err != nil {
+			return nil, errors.User(err, "cannot read gRPC service config file")
+		}
+		defer f.Close()
+
+		if g.grpcConf, err = parseGRPCConfig(f); err != nil {
+			return nil, errors.User(err, "error reading gRPC service config file %q", opts.grpcConfPath)
+		}
+	}
+
+	if opts.gapicConfPath != "" {
+		f, err := os.Open(opts.gapicConfPath)
+		if err != nil {
+			return nil, errors.User(err, "cannot read GAPIC config file")
+		}
+		defer f.Close()
+
+		if g.gapicConf, err = parseGAPICConfig(f); err != nil {
+			return nil, errors.User(err, "error reading GAPIC config file %q", opts.gapicConfPath)
+		}
+	}
+
+	var genFiles []*descriptor.FileDescriptorProto
 	var genServs []*descriptor.ServiceDescriptorProto
 	var eMeta *annotations.Metadata
 	for _, f := range genReq.ProtoFile {
 		if !strContains(genReq.FileToGenerate, f.GetName()) {
 			continue
 		}
+		genFiles = append(genFiles, f)
 		genServs = append(genServs, f.Service...)
 
 		// TODO(pongad): check if first-one-wins is the right strategy here.
@@ -71,44 +98,119 @@ func Gen(genReq *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse, er
 		}
 	}
 	if eMeta == nil {
-		return nil, errors.E(nil, "cannot find annotation %q: %v", annotations.E_Metadata.Name, genReq.FileToGenerate)
+		return nil, errors.User(nil, "cannot find annotation %q: %v", annotations.E_Metadata.Name, genReq.FileToGenerate)
 	}
 	g.apiName = strings.Join(eMeta.PackageNamespace, " ") + " " + eMeta.ProductName
 
-	for _, s := range genServs {
+	// Problems are collected rather than returned, so that all of them are reported at once;
+	// the code that cannot be generated is skipped.
+	var errs errors.List
+
+	planned, err := g.planNames(genServs, pkgName)
+	errs.Add(err)
+	for _, r := range g.names.renames {
+		log.Print(r)
+	}
+
+	// Iterators only depend on the type iterated over, so services of the package share them.
+	iters := map[string]*descriptor.FieldDescriptorProto{}
+	var okServs []*descriptor.ServiceDescriptorProto
+	for _, s := range planned {
 		// TODO(pongad): gapic-generator does not remove the package name here,
 		// so even though the client for LoggingServiceV2 is just "Client"
 		// the file name is "logging_client.go".
 		// Keep the current behavior for now, but we could revisit this later.
 		outFile := pbinfo.ReduceServName(s.GetName(), "")
-		outFile = camelToSnake(outFile)
+		outFile = naming.Snake(outFile)
 		outFile = filepath.Join(outDir, outFile)
 
 		g.reset()
-		if err := g.gen(s, pkgName); err != nil {
-			return nil, errors.E(err, "service: %s", s.GetName())
+		if err := g.gen(s, pkgName, iters); err != nil {
+			// The other files of the service would report the same problems.
+			errs.Add(errors.At(g.pos(s), err, "service %s", s.GetName()))
+			continue
 		}
 		g.commit(outFile+"_client.go", pkgName)
+		okServs = append(okServs, s)
 
 		g.reset()
 		if err := g.genExampleFile(s, pkgName); err != nil {
-			return nil, errors.E(err, "example: %s", s.GetName())
+			errs.Add(errors.At(g.pos(s), err, "example of service %s", s.GetName()))
+		} else {
+			g.imports[pbinfo.ImportSpec{Path: pkgPath}] = true
+			g.commit(outFile+"_client_example_test.go", pkgName+"_test")
+		}
+
+		if opts.hasTransport(restTransport) {
+			g.reset()
+			if err := g.genRESTClient(s, pbinfo.ReduceServName(s.GetName(), pkgName)); err != nil {
+				errs.Add(errors.At(g.pos(s), err, "REST client of service %s", s.GetName()))
+			} else {
+				g.commit(outFile+"_rest_client.go", pkgName)
+			}
+		}
+	}
+
+	if len(iters) > 0 {
+		g.reset()
+		if err := g.genAuxFile(iters); err != nil {
+			errs.Add(errors.E(err, "auxiliary types"))
+		} else {
+			g.commit(filepath.Join(outDir, "auxiliary.go"), pkgName)
+		}
+	}
+
+	if opts.hasTransport(grpcTransport) && len(okServs) > 0 {
+		g.reset()
+		if err := g.genMockFile(okServs, pkgName); err != nil {
+			errs.Add(errors.E(err, "mock test"))
+		} else {
+			g.commit(filepath.Join(outDir, "mock_test.go"), pkgName)
 		}
-		g.imports[pbinfo.ImportSpec{Path: pkgPath}] = true
-		g.commit(outFile+"_client_example_test.go", pkgName+"_test")
+	}
+
+	if opts.hasTransport(restTransport) && len(genServs) > 0 {
+		g.reset()
+		g.genRESTHelpers()
+		g.commit(filepath.Join(outDir, "rest.go"), pkgName)
+	}
+
+	if paths, err := collectResourcePaths(genFiles); err != nil {
+		errs.Add(err)
+	} else if len(paths) > 0 {
+		g.reset()
+		g.genPathFuncs(paths)
+		g.commit(filepath.Join(outDir, "path_funcs.go"), pkgName)
 	}
 
 	g.reset()
-	scopes, err := collectScopes(genServs)
-	if err != nil {
-		return nil, err
+	if scopes, err := collectScopes(genServs); err != nil {
+		errs.Add(err)
+	} else {
+		g.genDocFile(pkgPath, pkgName, time.Now().Year(), scopes)
+		g.resp.File = append(g.resp.File, &plugin.CodeGeneratorResponse_File{
+			Name:    proto.String(filepath.Join(outDir, "doc.go")),
+			Content: proto.String(g.pt.String()),
+		})
+	}
+
+	g.reset()
+	if err := g.genMetadataFile(genFiles[0].GetPackage(), pkgPath); err != nil {
+		errs.Add(err)
+	} else {
+		g.resp.File = append(g.resp.File, &plugin.CodeGeneratorResponse_File{
+			Name:    proto.String(filepath.Join(outDir, "gapic_metadata.json")),
+			Content: proto.String(g.pt.String()),
+		})
 	}
-	g.genDocFile(pkgPath, pkgName, time.Now().Year(), scopes)
-	g.resp.File = append(g.resp.File, &plugin.CodeGeneratorResponse_File{
-		Name:    proto.String(filepath.Join(outDir, "doc.go")),
-		Content: proto.String(g.pt.String()),
-	})
 
+	maxErrors := opts.maxErrors
+	if maxErrors == 0 {
+		maxErrors = defaultMaxErrors
+	}
+	if err := errs.Err(maxErrors); err != nil {
+		return nil, err
+	}
 	return &g.resp, nil
 }
 
@@ -124,17 +226,130 @@ func strContains(a []string, s string) bool {
 type generator struct {
 	pt printer.P
 
+	opts options
+
 	descInfo pbinfo.Info
 
+	// Retry and timeout settings read from the gRPC service config
+	grpcConf grpcConfig
+
+	// Paging settings read from the GAPIC config
+	gapicConf gapicConfig
+
 	// Maps proto elements to their comments
 	comments map[proto.Message]string
 
+	// Maps proto elements to their locations in the proto files,
+	// and options to the locations where they are set.
+	spans       map[proto.Message]sourceSpan
+	optionSpans map[optionKey]sourceSpan
+
 	resp plugin.CodeGeneratorResponse
 
 	imports map[pbinfo.ImportSpec]bool
 
 	// Human-readable name of the API used in docs
 	apiName string
+
+	// Signatures of the public methods of the client being generated,
+	// as they appear in an interface.
+	clientSigs []string
+
+	// Maps the import paths of proto packages to their names in the file being generated.
+	importNames map[string]string
+
+	// Names of the generated identifiers, planned before generating any file.
+	names names
+
+	// Methods generated for each RPC, written to gapic_metadata.json.
+	metadata gapicMetadata
+}
+
+// reservedNames maps the names used by generated code for other imports to their paths,
+// and the identifiers declared by generated code to the empty path.
+// Imports of proto packages are not given these names.
+var reservedNames = map[string]string{
+	"base64":        "encoding/base64",
+	"bytes":         "bytes",
+	"codes":         "google.golang.org/grpc/codes",
+	"context":       "golang.org/x/net/context",
+	"emptypb":       "github.com/golang/protobuf/ptypes/empty",
+	"fmt":           "fmt",
+	"gax":           "github.com/googleapis/gax-go",
+	"grpc":          "google.golang.org/grpc",
+	"gstatus":       "google.golang.org/grpc/status",
+	"htransport":    "google.golang.org/api/transport/http",
+	"http":          "net/http",
+	"io":            "io",
+	"iterator":      "google.golang.org/api/iterator",
+	"longrunning":   "cloud.google.com/go/longrunning",
+	"longrunningpb": "google.golang.org/genproto/googleapis/longrunning",
+	"lroauto":       "cloud.google.com/go/longrunning/autogen",
+	"math":          "math",
+	"metadata":      "google.golang.org/grpc/metadata",
+	"option":        "google.golang.org/api/option",
+	"proto":         "github.com/golang/protobuf/proto",
+	"ptypes":        "github.com/golang/protobuf/ptypes",
+	"status":        "google.golang.org/genproto/googleapis/rpc/status",
+	"strconv":       "strconv",
+	"strings":       "strings",
+	"time":          "time",
+	"transport":     "google.golang.org/api/transport",
+	"url":           "net/url",
+	"version":       "cloud.google.com/go/internal/version",
+
+	"anyResp": "", "bo": "", "c": "", "cancel": "", "ctx": "", "elems": "", "err": "", "errCode": "",
+	"expectedResponse": "", "it": "", "item": "", "md": "", "meta": "", "op": "", "opts": "",
+	"page": "", "req": "", "request": "", "resp": "", "respLRO": "", "settings": "", "stream": "",
+}
+
+// importSpec reports the import of the package containing e, named as in the file being generated.
+// The name is the one given by pbinfo.Info.ImportSpec, unless it is a Go keyword or already
+// names something else in the file, in which case the smallest number from 2 making it unique
+// is appended. Since the file is generated in a fixed order, so are the names.
+func (g *generator) importSpec(e proto.Message) (pbinfo.ImportSpec, error) {
+	spec, err := g.descInfo.ImportSpec(e)
+	if err != nil {
+		return pbinfo.ImportSpec{}, err
+	}
+	if name, ok := g.importNames[spec.Path]; ok {
+		spec.Name = name
+		return spec, nil
+	}
+
+	base := spec.Name
+	for i := 2; g.importNameTaken(spec); i++ {
+		spec.Name = base + strconv.Itoa(i)
+	}
+	if g.importNames == nil {
+		g.importNames = map[string]string{}
+	}
+	g.importNames[spec.Path] = spec.Name
+	return spec, nil
+}
+
+// importNameTaken reports whether spec.Name cannot name the import of spec.Path in the file being generated.
+func (g *generator) importNameTaken(spec pbinfo.ImportSpec) bool {
+	if token.Lookup(spec.Name).IsKeyword() || spec.Name == g.opts.pkgName {
+		return true
+	}
+	if path, ok := reservedNames[spec.Name]; ok && path != spec.Path {
+		return true
+	}
+	for path, name := range g.importNames {
+		if name == spec.Name && path != spec.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// fullyQualifiedName reports the fully-qualified name of e, without the leading dot.
+func (g *generator) fullyQualifiedName(e pbinfo.ProtoType) string {
+	if pkg := g.descInfo.ParentFile[e].GetPackage(); pkg != "" {
+		return pkg + "." + e.GetName()
+	}
+	return e.GetName()
 }
 
 func (g *generator) init(files []*descriptor.FileDescriptorProto) {
@@ -142,8 +357,11 @@ func (g *generator) init(files []*descriptor.FileDescriptorProto) {
 
 	g.comments = map[proto.Message]string{}
 	g.imports = map[pbinfo.ImportSpec]bool{}
+	g.spans = map[proto.Message]sourceSpan{}
+	g.optionSpans = map[optionKey]sourceSpan{}
 
 	for _, f := range files {
+		g.indexSpans(f)
 		for _, loc := range f.GetSourceCodeInfo().GetLocation() {
 			// p is an array with format [f1, i1, f2, i2, ...]
 			// - f1 refers to the protobuf field tag
@@ -237,25 +455,43 @@ func (g *generator) reset() {
 	for k := range g.imports {
 		delete(g.imports, k)
 	}
+	for k := range g.importNames {
+		delete(g.importNames, k)
+	}
 }
 
 // gen generates client for the given service.
-func (g *generator) gen(serv *descriptor.ServiceDescriptorProto, pkgName string) error {
+// The iterators needed by the client are added to iters, to be generated by genAuxFile.
+// If some methods cannot be generated, gen generates the others and returns the errors of all of them.
+func (g *generator) gen(serv *descriptor.ServiceDescriptorProto, pkgName string, iters map[string]*descriptor.FieldDescriptorProto) error {
 	servName := pbinfo.ReduceServName(*serv.Name, pkgName)
+	g.clientSigs = nil
+	var errs errors.List
 	if err := g.clientOptions(serv, servName); err != nil {
-		return err
+		errs.Add(err)
 	}
 	if err := g.clientInit(serv, servName); err != nil {
-		return err
+		errs.Add(err)
+	}
+
+	sigs, err := g.serviceSignatures(serv)
+	if err != nil {
+		errs.Add(err)
 	}
 
 	aux := auxTypes{
-		iters: map[string]iterType{},
+		iters: iters,
 	}
 	for _, m := range serv.Method {
 		g.methodDoc(m)
 		if err := g.genMethod(servName, serv, m, &aux); err != nil {
-			return errors.E(err, "method: %s", m.GetName())
+			errs.Add(errors.At(g.pos(m), err, "method %s", m.GetName()))
+			continue
+		}
+		for _, sig := range sigs[m] {
+			if err := g.flattenedCall(servName, serv, m, sig); err != nil {
+				errs.Add(errors.At(g.pos(m), err, "method %s: flattened method %s", m.GetName(), sig.name))
+			}
 		}
 	}
 
@@ -264,21 +500,50 @@ func (g *generator) gen(serv *descriptor.ServiceDescriptorProto, pkgName string)
 	})
 	for _, m := range aux.lros {
 		if err := g.lroType(servName, serv, m); err != nil {
-			return errors.E(err, "while generating LRO type for %q", m.GetName())
+			errs.Add(errors.At(g.pos(m), err, "method %s: operation type", m.GetName()))
 		}
 	}
 
-	var iters []iterType
-	for _, iter := range aux.iters {
-		iters = append(iters, iter)
+	sort.Slice(aux.clientStreams, func(i, j int) bool {
+		return aux.clientStreams[i].GetName() < aux.clientStreams[j].GetName()
+	})
+	for _, m := range aux.clientStreams {
+		if err := g.clientStreamType(serv, m); err != nil {
+			errs.Add(errors.At(g.pos(m), err, "method %s: stream type", m.GetName()))
+		}
 	}
-	sort.Slice(iters, func(i, j int) bool {
-		return iters[i].iterTypeName < iters[j].iterTypeName
+
+	sort.Slice(aux.pages, func(i, j int) bool {
+		return aux.pages[i].method.GetName() < aux.pages[j].method.GetName()
 	})
-	for _, iter := range iters {
-		g.pagingIter(iter)
+	for _, pt := range aux.pages {
+		if err := g.pageTypes(pt); err != nil {
+			errs.Add(errors.At(g.pos(pt.method), err, "method %s: page types", pt.method.GetName()))
+		}
 	}
 
+	if g.opts.clientInterface {
+		g.clientInterface(servName)
+	}
+	return errs.Err(0)
+}
+
+// genAuxFile generates the types shared by the clients of the package.
+// iters maps the names of iterators to the fields they iterate over.
+func (g *generator) genAuxFile(iters map[string]*descriptor.FieldDescriptorProto) error {
+	var keys []string
+	for key := range iters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// The element type is resolved again, since the names of imports differ between files.
+		iter, err := g.iterTypeOf(iters[key])
+		if err != nil {
+			return err
+		}
+		g.pagingIter(iter)
+	}
 	return nil
 }
 
@@ -288,9 +553,16 @@ type auxTypes struct {
 	lros []*descriptor.MethodDescriptorProto
 
 	// "List" of iterator types. We use these to generate FooIterator returned by paging methods.
-	// Since multiple methods can page over the same type, we dedupe by the name of the iterator,
-	// which is in turn determined by the element type name.
-	iters map[string]iterType
+	// Since multiple methods, possibly of different services, can page over the same type,
+	// we dedupe by the type iterated over, as given by iterKey.
+	// Each iterator maps to the field it iterates over.
+	iters map[string]*descriptor.FieldDescriptorProto
+
+	// List of client streaming methods. For each method "Foo", we use this to create the "FooStream" type.
+	clientStreams []*descriptor.MethodDescriptorProto
+
+	// List of paging methods. For each method "Foo", we use this to create the "FooPage" and "FooPageIterator" types.
+	pages []pageType
 }
 
 // genMethod generates a single method from a client. m must be a method declared in serv.
@@ -298,58 +570,75 @@ type auxTypes struct {
 func (g *generator) genMethod(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, aux *auxTypes) error {
 	if m.GetOutputType() == lroType {
 		aux.lros = append(aux.lros, m)
-		return g.lroCall(servName, m)
+		g.addMetadata(servName, serv, m, lroKind)
+		return g.lroCall(servName, serv, m)
 	}
 
 	if m.GetOutputType() == emptyType {
-		return g.emptyUnaryCall(servName, m)
+		g.addMetadata(servName, serv, m, unaryKind)
+		return g.emptyUnaryCall(servName, serv, m)
 	}
 
-	if pf, err := g.pagingField(m); err != nil {
+	if pi, err := g.pagingInfoOf(serv, m); err != nil {
 		return err
-	} else if pf != nil {
-		iter, err := g.iterTypeOf(pf)
+	} else if pi != nil {
+		iter, err := g.iterTypeOf(pi.elemField)
 		if err != nil {
 			return err
 		}
-		aux.iters[iter.iterTypeName] = iter
-		return g.pagingCall(servName, m, pf, iter)
+		aux.iters[g.iterKey(pi.elemField)] = pi.elemField
+		aux.pages = append(aux.pages, pageType{method: m, iter: iter})
+		g.addMetadata(servName, serv, m, pagedKind)
+		if err := g.pagingCall(servName, serv, m, pi, iter); err != nil {
+			return err
+		}
+		return g.pagesCall(servName, m, pi)
 	}
 
 	switch {
-	case m.GetClientStreaming():
+	case m.GetClientStreaming() && m.GetServerStreaming():
+		g.addMetadata(servName, serv, m, streamKind)
 		return g.noRequestStreamCall(servName, serv, m)
+	case m.GetClientStreaming():
+		aux.clientStreams = append(aux.clientStreams, m)
+		g.addMetadata(servName, serv, m, streamKind)
+		return g.clientStreamCall(servName, serv, m)
 	case m.GetServerStreaming():
+		g.addMetadata(servName, serv, m, streamKind)
 		return g.serverStreamCall(servName, serv, m)
 	default:
-		return g.unaryCall(servName, m)
+		g.addMetadata(servName, serv, m, unaryKind)
+		return g.unaryCall(servName, serv, m)
 	}
 }
 
-func (g *generator) unaryCall(servName string, m *descriptor.MethodDescriptorProto) error {
+func (g *generator) unaryCall(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
 	inType := g.descInfo.Type[*m.InputType]
 	outType := g.descInfo.Type[*m.OutputType]
 
-	inSpec, err := g.descInfo.ImportSpec(inType)
+	inSpec, err := g.importSpec(inType)
 	if err != nil {
 		return err
 	}
-	outSpec, err := g.descInfo.ImportSpec(outType)
+	outSpec, err := g.importSpec(outType)
 	if err != nil {
 		return err
 	}
 
 	p := g.printf
 
-	p("func (c *%sClient) %s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) (*%s.%s, error) {",
-		servName, *m.Name, inSpec.Name, inType.GetName(), outSpec.Name, outType.GetName())
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) (*%s.%s, error)",
+		g.clientMethodName(m), inSpec.Name, inType.GetName(), outSpec.Name, outType.GetName()))
 
-	g.insertMetadata()
+	if err := g.insertMetadata(m); err != nil {
+		return err
+	}
 	g.appendCallOpts(m)
+	g.callTimeout(serv, m, false)
 	p("var resp *%s.%s", outSpec.Name, outType.GetName())
 	p("err := gax.Invoke(ctx, func(ctx context.Context, settings gax.CallSettings) error {")
 	p("  var err error")
-	p("  resp, err = %s", grpcClientCall(servName, *m.Name))
+	p("  resp, err = %s", g.grpcClientCall(servName, *m.Name))
 	p("  return err")
 	p("}, opts...)")
 	p("if err != nil {")
@@ -366,24 +655,27 @@ func (g *generator) unaryCall(servName string, m *descriptor.MethodDescriptorPro
 	return nil
 }
 
-func (g *generator) emptyUnaryCall(servName string, m *descriptor.MethodDescriptorProto) error {
+func (g *generator) emptyUnaryCall(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
 	inType := g.descInfo.Type[*m.InputType]
 
-	inSpec, err := g.descInfo.ImportSpec(inType)
+	inSpec, err := g.importSpec(inType)
 	if err != nil {
 		return err
 	}
 
 	p := g.printf
 
-	p("func (c *%sClient) %s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) error {",
-		servName, m.GetName(), inSpec.Name, inType.GetName())
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) error",
+		g.clientMethodName(m), inSpec.Name, inType.GetName()))
 
-	g.insertMetadata()
+	if err := g.insertMetadata(m); err != nil {
+		return err
+	}
 	g.appendCallOpts(m)
+	g.callTimeout(serv, m, false)
 	p("err := gax.Invoke(ctx, func(ctx context.Context, settings gax.CallSettings) error {")
 	p("  var err error")
-	p("  _, err = %s", grpcClientCall(servName, m.GetName()))
+	p("  _, err = %s", g.grpcClientCall(servName, m.GetName()))
 	p("  return err")
 	p("}, opts...)")
 	p("return err")
@@ -395,14 +687,139 @@ func (g *generator) emptyUnaryCall(servName string, m *descriptor.MethodDescript
 	return nil
 }
 
-func (g *generator) insertMetadata() {
-	g.printf("ctx = insertMetadata(ctx, c.xGoogMetadata)")
+// clientMethod prints the header of a public method of the client of servName.
+// sig is the name, parameters and results of the method.
+func (g *generator) clientMethod(servName, sig string) {
+	g.printf("func (c *%sClient) %s {", servName, sig)
+	g.clientSigs = append(g.clientSigs, sig)
+}
+
+// clientInterface generates the interface of the client of servName,
+// with the methods recorded by clientMethod.
+func (g *generator) clientInterface(servName string) {
+	p := g.printf
+
+	p("// %[1]sClientAPI is the interface implemented by %[1]sClient.", servName)
+	p("// It can be used to substitute the client in tests.")
+	p("type %sClientAPI interface {", servName)
+	for _, sig := range g.clientSigs {
+		p("%s", sig)
+	}
+	p("}")
+	p("")
+	p("var _ %[1]sClientAPI = (*%[1]sClient)(nil)", servName)
+	p("")
+}
+
+// insertMetadata prints the code adding the x-goog-* metadata to the outgoing context of a call to m.
+// The variables in the path of the HTTP rule of m are sent in the x-goog-request-params header,
+// so the backend can route the request by their values.
+// The header is best-effort: if the HTTP rule is not supported, the method is generated without it.
+func (g *generator) insertMetadata(m *descriptor.MethodDescriptorProto) error {
+	var parts []string
+	if !m.GetClientStreaming() {
+		var err error
+		parts, err = g.routingHeader(m)
+		if errors.IsUser(err) {
+			log.Printf("warning: method %s is generated without the x-goog-request-params header: %v", m.GetName(), err)
+			parts = nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(parts) == 0 {
+		g.printf("ctx = insertMetadata(ctx, c.xGoogMetadata)")
+		return nil
+	}
+
+	g.printf(`md := metadata.Pairs("x-goog-request-params", %s)`, strings.Join(parts, " + "))
+	g.printf("ctx = insertMetadata(ctx, c.xGoogMetadata, md)")
+
+	g.imports[pbinfo.ImportSpec{Path: "net/url"}] = true
+	g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc/metadata"}] = true
+	return nil
+}
+
+// routingHeader returns the expressions concatenated into the value of the x-goog-request-params header of m.
+func (g *generator) routingHeader(m *descriptor.MethodDescriptorProto) ([]string, error) {
+	params, err := g.routingParams(m)
+	if err != nil || len(params) == 0 {
+		return nil, err
+	}
+
+	inMsg, ok := g.descInfo.Type[m.GetInputType()].(*descriptor.DescriptorProto)
+	if !ok {
+		return nil, errors.E(nil, "cannot find message type %q, malformed descriptor?", m.GetInputType())
+	}
+
+	var parts []string
+	for i, fieldPath := range params {
+		val, err := g.pathVarValue(inMsg, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		key := fieldPath + "="
+		if i > 0 {
+			key = "&" + key
+		}
+		parts = append(parts, strconv.Quote(key), fmt.Sprintf("url.QueryEscape(%s)", val))
+	}
+	return parts, nil
+}
+
+// routingParams reports the field paths of the variables in the path of the HTTP rule of m,
+// without duplicates. It returns nil if m has no HTTP rule.
+func (g *generator) routingParams(m *descriptor.MethodDescriptorProto) ([]string, error) {
+	rule, err := httpRule(m)
+	if err != nil || rule == nil {
+		return nil, err
+	}
+	_, tmpl, err := httpVerbPath(rule)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := parsePathTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var params []string
+	seen := map[string]bool{}
+	for _, v := range pt.vars {
+		if !seen[v.fieldPath] {
+			seen[v.fieldPath] = true
+			params = append(params, v.fieldPath)
+		}
+	}
+	return params, nil
 }
 
 func (g *generator) appendCallOpts(m *descriptor.MethodDescriptorProto) {
 	g.printf("opts = append(%[1]s[0:len(%[1]s):len(%[1]s)], opts...)", "c.CallOptions."+*m.Name)
 }
 
+// callTimeout prints the code giving ctx the timeout of method m of serv set in the gRPC service config, if any,
+// unless ctx already has a deadline. The timeout covers the call, retries included.
+// gax has no call option for timeouts, so they are not part of the call options of the method.
+// If shadow is set, a new ctx is declared, leaving the one in scope unchanged.
+func (g *generator) callTimeout(serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, shadow bool) {
+	mc := g.grpcConf.methodConfig(g.fullyQualifiedName(serv), m.GetName())
+	if mc == nil || mc.timeout <= 0 {
+		return
+	}
+
+	p := g.printf
+	if shadow {
+		p("ctx := ctx")
+	}
+	p("if _, ok := ctx.Deadline(); !ok {")
+	p("  var cancel context.CancelFunc")
+	p("  ctx, cancel = context.WithTimeout(ctx, %s)", durationExpr(mc.timeout))
+	p("  defer cancel()")
+	p("}")
+	g.imports[pbinfo.ImportSpec{Path: "time"}] = true
+}
+
 func (g *generator) methodDoc(m *descriptor.MethodDescriptorProto) {
 	com := g.comments[m]
 	com = strings.TrimSpace(com)
@@ -412,7 +829,7 @@ func (g *generator) methodDoc(m *descriptor.MethodDescriptorProto) {
 		return
 	}
 
-	g.comment(*m.Name + " " + lowerFirst(com))
+	g.comment(g.clientMethodName(m) + " " + lowerFirst(com))
 }
 
 func (g *generator) comment(s string) {
@@ -434,7 +851,8 @@ func (g *generator) comment(s string) {
 	}
 }
 
-// grpcClientField reports the field name to store gRPC client.
+// grpcClientField reports the default field name to store gRPC client.
+// Use the method of the same name of generator, which applies the names planned by planNames.
 func grpcClientField(reducedServName string) string {
 	// Not the same as pbinfo.ReduceServName(*serv.Name, pkg)+"Client".
 	// If the service name is reduced to empty string, we should
@@ -442,10 +860,6 @@ func grpcClientField(reducedServName string) string {
 	return lowerFirst(reducedServName + "Client")
 }
 
-func grpcClientCall(reducedServName, methName string) string {
-	return fmt.Sprintf("c.%s.%s(ctx, req, settings.GRPC...)", grpcClientField(reducedServName), methName)
-}
-
 func lowerFirst(s string) string {
 	if s == "" {
 		return ""
@@ -461,31 +875,3 @@ func upperFirst(s string) string {
 	r, w := utf8.DecodeRuneInString(s)
 	return string(unicode.ToUpper(r)) + s[w:]
 }
-
-func camelToSnake(s string) string {
-	var sb strings.Builder
-	for i, r := range s {
-		if unicode.IsUpper(r) && i != 0 {
-			sb.WriteByte('_')
-		}
-		sb.WriteRune(unicode.ToLower(r))
-	}
-	return sb.String()
-}
-
-// snakeToCamel converts snake_case and SNAKE_CASE to CamelCase.
-func snakeToCamel(s string) string {
-	var sb strings.Builder
-	up := true
-	for _, r := range s {
-		if r == '_' {
-			up = true
-		} else if up {
-			sb.WriteRune(unicode.ToUpper(r))
-			up = false
-		} else {
-			sb.WriteRune(unicode.ToLower(r))
-		}
-	}
-	return sb.String()
-}
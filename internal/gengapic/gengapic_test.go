@@ -15,11 +15,17 @@
 package gengapic
 
 import (
+	"io/ioutil"
+	"log"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
 	"google.golang.org/genproto/googleapis/api/annotations"
 )
@@ -104,13 +110,20 @@ func TestGRPCClientField(t *testing.T) {
 	for _, tst := range []struct {
 		in, pkg, want string
 	}{
-		{"Foo", "foo", "client"},
-		{"FooV2", "foo", "client"},
-		{"FooService", "foo", "client"},
-		{"FooServiceV2", "foo", "client"},
+		// Services whose names reduce to nothing name the field after the full service name,
+		// rather than the ambiguous "client".
+		{"Foo", "foo", "fooClient"},
+		{"FooV2", "foo", "fooV2Client"},
+		{"FooService", "foo", "fooServiceClient"},
+		{"FooServiceV2", "foo", "fooServiceV2Client"},
 		{"FooV2Bar", "", "fooV2BarClient"},
 	} {
-		if got := grpcClientField(pbinfo.ReduceServName(tst.in, tst.pkg)); got != tst.want {
+		var g generator
+		serv := &descriptor.ServiceDescriptorProto{Name: proto.String(tst.in)}
+		if _, err := g.planNames([]*descriptor.ServiceDescriptorProto{serv}, tst.pkg); err != nil {
+			t.Fatal(err)
+		}
+		if got := g.grpcClientField(pbinfo.ReduceServName(tst.in, tst.pkg)); got != tst.want {
 			t.Errorf("grpcClientField(pbinfo.ReduceServName(%q, %q)) = %q, want %q", tst.in, tst.pkg, got, tst.want)
 		}
 	}
@@ -162,26 +175,111 @@ func TestGenMethod(t *testing.T) {
 		},
 	}
 
+	stateType := &descriptor.EnumDescriptorProto{
+		Name: proto.String("State"),
+	}
+	statePageOutputType := &descriptor.DescriptorProto{
+		Name: proto.String("StatePageOutputType"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{
+				Name:  proto.String("next_page_token"),
+				Type:  typep(descriptor.FieldDescriptorProto_TYPE_STRING),
+				Label: labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL),
+			},
+			{
+				Name:     proto.String("states"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_ENUM),
+				TypeName: proto.String(".my.pkg.State"),
+				Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		},
+	}
+	itemsEntry := &descriptor.DescriptorProto{
+		Name: proto.String("ItemsEntry"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{
+				Name:  proto.String("key"),
+				Type:  typep(descriptor.FieldDescriptorProto_TYPE_STRING),
+				Label: labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL),
+			},
+			{
+				Name:     proto.String("value"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String(".my.pkg.OutputType"),
+				Label:    labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL),
+			},
+		},
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	mapPageOutputType := &descriptor.DescriptorProto{
+		Name: proto.String("MapPageOutputType"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{
+				Name:  proto.String("next_page_token"),
+				Type:  typep(descriptor.FieldDescriptorProto_TYPE_STRING),
+				Label: labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL),
+			},
+			{
+				Name:     proto.String("items"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String(".my.pkg.MapPageOutputType.ItemsEntry"),
+				Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		},
+		NestedType: []*descriptor.DescriptorProto{itemsEntry},
+	}
+
 	file := &descriptor.FileDescriptorProto{
 		Package: proto.String("my.pkg"),
 		Options: &descriptor.FileOptions{
 			GoPackage: proto.String("mypackage"),
 		},
 	}
-	serv := &descriptor.ServiceDescriptorProto{}
+	serv := &descriptor.ServiceDescriptorProto{Name: proto.String("Foo")}
 
 	var g generator
 	g.imports = map[pbinfo.ImportSpec]bool{}
 
+	// Streaming methods ignore the timeout, the others apply it to each call.
+	conf, err := parseGRPCConfig(strings.NewReader(`{
+		"methodConfig": [{
+			"name": [
+				{"service": "my.pkg.Foo", "method": "GetOneThing"},
+				{"service": "my.pkg.Foo", "method": "DeleteBigThing"},
+				{"service": "my.pkg.Foo", "method": "GetManyThings"},
+				{"service": "my.pkg.Foo", "method": "ServerThings"}
+			],
+			"timeout": "1.5s"
+		}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.grpcConf = conf
+
 	commonTypes(&g)
 	for _, typ := range []*descriptor.DescriptorProto{
-		inputType, outputType, pageInputType, pageOutputType,
+		inputType, outputType, pageInputType, pageOutputType, statePageOutputType, mapPageOutputType,
 	} {
 		g.descInfo.Type[".my.pkg."+*typ.Name] = typ
 		g.descInfo.ParentFile[typ] = file
 	}
+	g.descInfo.Type[".my.pkg.State"] = stateType
+	g.descInfo.ParentFile[stateType] = file
+	g.descInfo.Type[".my.pkg.MapPageOutputType.ItemsEntry"] = itemsEntry
+	g.descInfo.ParentElement = map[pbinfo.ProtoType]pbinfo.ProtoType{
+		itemsEntry: mapPageOutputType,
+	}
 	g.descInfo.ParentFile[serv] = file
 
+	emptyLROOpts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(emptyLROOpts, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+		Response: "google.protobuf.Empty",
+		Metadata: "google.protobuf.Empty",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
 	meths := []*descriptor.MethodDescriptorProto{
 		{
 			Name:       proto.String("GetEmptyThing"),
@@ -199,11 +297,27 @@ func TestGenMethod(t *testing.T) {
 			OutputType: proto.String(".google.longrunning.Operation"),
 			Options:    &descriptor.MethodOptions{},
 		},
+		{
+			Name:       proto.String("DeleteBigThing"),
+			InputType:  proto.String(".my.pkg.InputType"),
+			OutputType: proto.String(".google.longrunning.Operation"),
+			Options:    emptyLROOpts,
+		},
 		{
 			Name:       proto.String("GetManyThings"),
 			InputType:  proto.String(".my.pkg.PageInputType"),
 			OutputType: proto.String(".my.pkg.PageOutputType"),
 		},
+		{
+			Name:       proto.String("GetManyStates"),
+			InputType:  proto.String(".my.pkg.PageInputType"),
+			OutputType: proto.String(".my.pkg.StatePageOutputType"),
+		},
+		{
+			Name:       proto.String("GetManyMappedThings"),
+			InputType:  proto.String(".my.pkg.PageInputType"),
+			OutputType: proto.String(".my.pkg.MapPageOutputType"),
+		},
 		{
 			Name:            proto.String("ServerThings"),
 			InputType:       proto.String(".my.pkg.InputType"),
@@ -230,7 +344,7 @@ methods:
 		g.pt.Reset()
 
 		// Just add this everywhere. Only LRO method will pick it up.
-		if m.Options != nil {
+		if m.Options != nil && !proto.HasExtension(m.Options, annotations.E_LongrunningOperationTypes) {
 			lroType := &annotations.LongrunningOperationTypes{
 				Response: "OutputType",
 			}
@@ -238,7 +352,7 @@ methods:
 		}
 
 		aux := auxTypes{
-			iters: map[string]iterType{},
+			iters: map[string]*descriptor.FieldDescriptorProto{},
 		}
 		if err := g.genMethod("Foo", serv, m, &aux); err != nil {
 			t.Error(err)
@@ -252,10 +366,442 @@ methods:
 			}
 		}
 
-		for _, iter := range aux.iters {
-			g.pagingIter(iter)
+		for _, m := range aux.clientStreams {
+			if err := g.clientStreamType(serv, m); err != nil {
+				t.Error(err)
+				continue methods
+			}
+		}
+
+		if err := g.genAuxFile(aux.iters); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		for _, pt := range aux.pages {
+			if err := g.pageTypes(pt); err != nil {
+				t.Error(err)
+				continue methods
+			}
 		}
 
 		diff(t, m.GetName(), g.pt.String(), filepath.Join("testdata", "method_"+m.GetName()+".want"))
 	}
 }
+
+func TestRoutingHeaders(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	serv := restService(&g)
+
+	for _, m := range serv.Method {
+		switch m.GetName() {
+		case "GetThing", "UpdateThing", "PurgeThing", "BidiThings":
+		default:
+			continue
+		}
+		aux := auxTypes{
+			iters: map[string]*descriptor.FieldDescriptorProto{},
+		}
+		if err := g.genMethod("Foo", serv, m, &aux); err != nil {
+			t.Fatal(err)
+		}
+	}
+	diff(t, "routing_headers", g.pt.String(), filepath.Join("testdata", "routing_headers.want"))
+}
+
+func TestRoutingHeadersUnsupported(t *testing.T) {
+	// Each method logs a warning.
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, rule := range []*annotations.HttpRule{
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{filter}"}},
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{ids}"}},
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name.foo}"}},
+		{Pattern: &annotations.HttpRule_Custom{Custom: &annotations.CustomHttpPattern{Kind: "PURGE", Path: "/v1/{name=things/*"}}},
+		{},
+	} {
+		var g generator
+		g.imports = map[pbinfo.ImportSpec]bool{}
+		serv := restService(&g)
+		m := serv.Method[0]
+		if err := proto.SetExtension(m.Options, annotations.E_Http, rule); err != nil {
+			t.Fatal(err)
+		}
+
+		// The method is generated without the header.
+		aux := auxTypes{
+			iters: map[string]*descriptor.FieldDescriptorProto{},
+		}
+		if err := g.genMethod("Foo", serv, m, &aux); err != nil {
+			t.Errorf("rule %v: %v", rule, err)
+			continue
+		}
+		if got := g.pt.String(); !strings.Contains(got, "ctx = insertMetadata(ctx, c.xGoogMetadata)\n") || strings.Contains(got, "x-goog-request-params") {
+			t.Errorf("rule %v: got\n%s\nwant no x-goog-request-params header", rule, got)
+		}
+	}
+}
+
+func TestClientInterface(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	g.opts.clientInterface = true
+	serv := signatureService(&g)
+	serv.Options = &descriptor.ServiceOptions{}
+	if err := proto.SetExtension(serv.Options, annotations.E_DefaultHost, proto.String("foo.googleapis.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+
+	listReq := &descriptor.DescriptorProto{
+		Name: proto.String("ListThingsRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("page_size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT32), Label: optional},
+			{Name: proto.String("page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+		},
+	}
+	listResp := &descriptor.DescriptorProto{
+		Name: proto.String("ListThingsResponse"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("next_page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{
+				Name:     proto.String("things"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String(".my.pkg.Thing"),
+				Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		},
+	}
+	file := g.descInfo.ParentFile[serv]
+	for _, typ := range []*descriptor.DescriptorProto{listReq, listResp} {
+		g.descInfo.Type[".my.pkg."+typ.GetName()] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+
+	lroOpts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(lroOpts, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+		Response: "Thing",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	serv.Method = append(serv.Method,
+		&descriptor.MethodDescriptorProto{Name: proto.String("DeleteThing"), InputType: proto.String(".my.pkg.Thing"), OutputType: proto.String(emptyType)},
+		&descriptor.MethodDescriptorProto{Name: proto.String("ListThings"), InputType: proto.String(".my.pkg.ListThingsRequest"), OutputType: proto.String(".my.pkg.ListThingsResponse")},
+		&descriptor.MethodDescriptorProto{Name: proto.String("BuildThing"), InputType: proto.String(".my.pkg.Thing"), OutputType: proto.String(lroType), Options: lroOpts},
+		&descriptor.MethodDescriptorProto{Name: proto.String("WatchThings"), InputType: proto.String(".my.pkg.Thing"), OutputType: proto.String(".my.pkg.Thing"), ServerStreaming: proto.Bool(true)},
+		&descriptor.MethodDescriptorProto{Name: proto.String("UploadThings"), InputType: proto.String(".my.pkg.Thing"), OutputType: proto.String(".my.pkg.Thing"), ClientStreaming: proto.Bool(true)},
+	)
+
+	if err := g.gen(serv, "mypackage", map[string]*descriptor.FieldDescriptorProto{}); err != nil {
+		t.Fatal(err)
+	}
+	got := g.pt.String()
+	i := strings.Index(got, "// FooClientAPI ")
+	if i < 0 {
+		t.Fatalf("FooClientAPI not generated:\n%s", got)
+	}
+	diff(t, "client_interface", got[i:], filepath.Join("testdata", "client_interface.want"))
+}
+
+func TestSharedIterators(t *testing.T) {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+	listResp := func(name, elemType string) *descriptor.DescriptorProto {
+		return &descriptor.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptor.FieldDescriptorProto{
+				{Name: proto.String("next_page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+				{
+					Name:     proto.String("things"),
+					Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+					TypeName: proto.String(elemType),
+					Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+				},
+			},
+		}
+	}
+
+	thing := &descriptor.DescriptorProto{Name: proto.String("Thing")}
+	listReq := &descriptor.DescriptorProto{
+		Name: proto.String("ListThingsRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("page_size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT32), Label: optional},
+			{Name: proto.String("page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+		},
+	}
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+	// A type of the same name as Thing, in another proto package.
+	otherThing := &descriptor.DescriptorProto{Name: proto.String("Thing")}
+	otherFile := &descriptor.FileDescriptorProto{
+		Package: proto.String("other.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("example.com/other;other"),
+		},
+	}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	commonTypes(&g)
+	for _, typ := range []*descriptor.DescriptorProto{
+		thing,
+		listReq,
+		listResp("ListThingsResponse", ".my.pkg.Thing"),
+		listResp("ListOtherThingsResponse", ".other.pkg.Thing"),
+	} {
+		g.descInfo.Type[".my.pkg."+typ.GetName()] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+	g.descInfo.Type[".other.pkg.Thing"] = otherThing
+	g.descInfo.ParentFile[otherThing] = otherFile
+
+	// Both services declare ListThings and Upload, generating types of the same names,
+	// and Bar also iterates over the other Thing.
+	want := map[string][]string{
+		"Foo": {"type ListThingsPage struct", "type ListThingsPageIterator struct", "type UploadStream struct"},
+		"Bar": {"type ListThingsPage_ struct", "type ListThingsPageIterator_ struct", "type UploadStream_ struct", "*ThingIterator_"},
+	}
+	var servs []*descriptor.ServiceDescriptorProto
+	for _, name := range []string{"Foo", "Bar"} {
+		serv := &descriptor.ServiceDescriptorProto{
+			Name: proto.String(name),
+			Method: []*descriptor.MethodDescriptorProto{
+				{Name: proto.String("ListThings"), InputType: proto.String(".my.pkg.ListThingsRequest"), OutputType: proto.String(".my.pkg.ListThingsResponse")},
+				{Name: proto.String("Upload"), InputType: proto.String(".my.pkg.Thing"), OutputType: proto.String(".my.pkg.Thing"), ClientStreaming: proto.Bool(true)},
+			},
+			Options: &descriptor.ServiceOptions{},
+		}
+		if name == "Bar" {
+			serv.Method = append(serv.Method, &descriptor.MethodDescriptorProto{
+				Name: proto.String("ListOtherThings"), InputType: proto.String(".my.pkg.ListThingsRequest"), OutputType: proto.String(".my.pkg.ListOtherThingsResponse"),
+			})
+		}
+		if err := proto.SetExtension(serv.Options, annotations.E_DefaultHost, proto.String("foo.googleapis.com")); err != nil {
+			t.Fatal(err)
+		}
+		g.descInfo.ParentFile[serv] = file
+		servs = append(servs, serv)
+	}
+	if _, err := g.planNames(servs, "mypackage"); err != nil {
+		t.Fatal(err)
+	}
+
+	iters := map[string]*descriptor.FieldDescriptorProto{}
+	for _, serv := range servs {
+		name := serv.GetName()
+		g.reset()
+		if err := g.gen(serv, "mypackage", iters); err != nil {
+			t.Fatal(err)
+		}
+		got := g.pt.String()
+		if strings.Contains(got, "type ThingIterator struct") {
+			t.Errorf("client of %s defines ThingIterator, want it in the auxiliary file", name)
+		}
+		for _, w := range want[name] {
+			if !strings.Contains(got, w) {
+				t.Errorf("client of %s: cannot find %q", name, w)
+			}
+		}
+		if name == "Bar" && strings.Contains(got, "type ListThingsPage struct") {
+			t.Errorf("client of Bar defines ListThingsPage, already defined by the client of Foo")
+		}
+	}
+
+	g.reset()
+	if err := g.genAuxFile(iters); err != nil {
+		t.Fatal(err)
+	}
+	got := g.pt.String()
+	for _, w := range []string{"type ThingIterator struct", "type ThingIterator_ struct", "func (it *ThingIterator_) Next() (*otherpb.Thing, error)"} {
+		if n := strings.Count(got, w); n != 1 {
+			t.Errorf("auxiliary file has %d %q, want 1\n%s", n, w, got)
+		}
+	}
+}
+
+func TestImportSpecCollisions(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	g.opts.pkgName = "mainpb"
+	g.descInfo.ParentFile = map[proto.Message]*descriptor.FileDescriptorProto{}
+
+	msg := func(goPkg string) *descriptor.DescriptorProto {
+		m := &descriptor.DescriptorProto{Name: proto.String("Thing")}
+		g.descInfo.ParentFile[m] = &descriptor.FileDescriptorProto{
+			Options: &descriptor.FileOptions{GoPackage: proto.String(goPkg)},
+		}
+		return m
+	}
+	loggingType := msg("google.golang.org/genproto/googleapis/logging/type")
+	fooType := msg("google.golang.org/genproto/googleapis/foo/type")
+	otherLRO := msg("example.com/longrunning")
+	lro := msg("google.golang.org/genproto/googleapis/longrunning")
+	mainPkg := msg("example.com/main;main")
+
+	for i, tst := range []struct {
+		msg  *descriptor.DescriptorProto
+		want string
+	}{
+		{loggingType, "typepb"},
+		{fooType, "typepb2"},
+		{loggingType, "typepb"},
+		{otherLRO, "longrunningpb2"},
+		{lro, "longrunningpb"},
+		{mainPkg, "mainpb2"},
+	} {
+		spec, err := g.importSpec(tst.msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spec.Name != tst.want {
+			t.Errorf("%d: got import name %q, want %q", i, spec.Name, tst.want)
+		}
+	}
+
+	// Names are allocated again for the next file.
+	g.reset()
+	if spec, err := g.importSpec(fooType); err != nil {
+		t.Fatal(err)
+	} else if spec.Name != "typepb" {
+		t.Errorf("after reset, got import name %q, want %q", spec.Name, "typepb")
+	}
+}
+
+func TestGenCollectsErrors(t *testing.T) {
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+	req := &descriptor.DescriptorProto{Name: proto.String("Request")}
+	resp := &descriptor.DescriptorProto{Name: proto.String("Response")}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	commonTypes(&g)
+	for _, typ := range []*descriptor.DescriptorProto{req, resp} {
+		g.descInfo.Type[".my.pkg."+typ.GetName()] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+
+	// Paging is configured with fields the messages do not have.
+	badPaging := &gapicMethodConfig{paging: &pagingConfig{tokenField: "page_token", nextTokenField: "next_page_token", resourcesField: "things"}}
+	g.gapicConf = gapicConfig{methods: map[string]map[string]*gapicMethodConfig{
+		"my.pkg.Foo": {"ListThings": badPaging, "ListOthers": badPaging},
+	}}
+
+	serv := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("Foo"),
+		Method: []*descriptor.MethodDescriptorProto{
+			{Name: proto.String("ListThings"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response")},
+			{Name: proto.String("GetThing"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response")},
+			{Name: proto.String("ListOthers"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response")},
+		},
+		Options: &descriptor.ServiceOptions{},
+	}
+	if err := proto.SetExtension(serv.Options, annotations.E_DefaultHost, proto.String("foo.googleapis.com")); err != nil {
+		t.Fatal(err)
+	}
+	g.descInfo.ParentFile[serv] = file
+
+	err := g.gen(serv, "mypackage", map[string]*descriptor.FieldDescriptorProto{})
+	if err == nil {
+		t.Fatal("gen succeeded, want errors of ListThings and ListOthers")
+	}
+	for _, want := range []string{"method ListThings", "method ListOthers"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if !errors.IsUser(err) {
+		t.Errorf("IsUser(%q) = false, want true", err)
+	}
+	if got, want := g.pt.String(), "func (c *FooClient) GetThing("; !strings.Contains(got, want) {
+		t.Errorf("client does not contain %q, the methods after a broken one should be generated", want)
+	}
+}
+
+func TestGenCollectsPlanningErrors(t *testing.T) {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional, repeated := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL), labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED)
+
+	// ListResponse looks paginated, but has two repeated fields to page over.
+	file := &descriptor.FileDescriptorProto{
+		Name:    proto.String("foo.proto"),
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("ListRequest"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("page_size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT32), Label: optional},
+					{Name: proto.String("page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+				},
+			},
+			{
+				Name: proto.String("ListResponse"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("next_page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+					{Name: proto.String("things"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: repeated},
+					{Name: proto.String("others"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: repeated},
+				},
+			},
+		},
+	}
+	if err := proto.SetExtension(file.Options, annotations.E_Metadata, &annotations.Metadata{ProductName: "Foo"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Foo", "Bar"} {
+		serv := &descriptor.ServiceDescriptorProto{
+			Name: proto.String(name),
+			Method: []*descriptor.MethodDescriptorProto{
+				{Name: proto.String("List" + name + "s"), InputType: proto.String(".my.pkg.ListRequest"), OutputType: proto.String(".my.pkg.ListResponse")},
+			},
+			Options: &descriptor.ServiceOptions{},
+		}
+		if err := proto.SetExtension(serv.Options, annotations.E_DefaultHost, proto.String("foo.googleapis.com")); err != nil {
+			t.Fatal(err)
+		}
+		file.Service = append(file.Service, serv)
+	}
+
+	_, err := Gen(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo.proto"},
+		Parameter:      proto.String("package-path=example.com/things"),
+		ProtoFile:      []*descriptor.FileDescriptorProto{file},
+	})
+	if err == nil {
+		t.Fatal("Gen succeeded, want errors of ListFoos and ListBars")
+	}
+	for _, want := range []string{"method ListFoos", "method ListBars"} {
+		if n := strings.Count(err.Error(), want); n != 1 {
+			t.Errorf("error %q contains %q %d times, want once", err, want, n)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"google.golang.org/genproto/googleapis/rpc/code"
+)
+
+// grpcConfig contains the per-method settings from a gRPC service config.
+// See https://github.com/grpc/grpc/blob/master/doc/service_config.md for the format.
+type grpcConfig struct {
+	// Maps fully-qualified service name, then method name, to the method's config.
+	// A config that applies to all methods of a service is keyed by the empty method name.
+	methods map[string]map[string]*methodConfig
+}
+
+// methodConfig is the config applied to calls of a method.
+type methodConfig struct {
+	// Deadline of each call. Zero means no deadline.
+	timeout time.Duration
+
+	// If nil, the method is not retried.
+	retry *retryPolicy
+}
+
+type retryPolicy struct {
+	initialBackoff, maxBackoff time.Duration
+	backoffMultiplier          float64
+	codes                      []code.Code
+}
+
+// JSON representation of the service config.
+// We only read the parts relevant to the generated clients.
+type jsonGRPCConfig struct {
+	MethodConfig []struct {
+		Name []struct {
+			Service string `json:"service"`
+			Method  string `json:"method"`
+		} `json:"name"`
+		Timeout     string `json:"timeout"`
+		RetryPolicy *struct {
+			InitialBackoff       string   `json:"initialBackoff"`
+			MaxBackoff           string   `json:"maxBackoff"`
+			BackoffMultiplier    float64  `json:"backoffMultiplier"`
+			RetryableStatusCodes []string `json:"retryableStatusCodes"`
+		} `json:"retryPolicy"`
+	} `json:"methodConfig"`
+}
+
+// parseGRPCConfig reads a gRPC service config in JSON format from r.
+func parseGRPCConfig(r io.Reader) (grpcConfig, error) {
+	var js jsonGRPCConfig
+	if err := json.NewDecoder(r).Decode(&js); err != nil {
+		return grpcConfig{}, errors.E(err, "cannot decode gRPC service config")
+	}
+
+	conf := grpcConfig{methods: map[string]map[string]*methodConfig{}}
+	for i, jmc := range js.MethodConfig {
+		var mc methodConfig
+
+		if jmc.Timeout != "" {
+			t, err := time.ParseDuration(jmc.Timeout)
+			if err != nil {
+				return grpcConfig{}, errors.E(err, "methodConfig[%d]: bad timeout", i)
+			}
+			mc.timeout = t
+		}
+
+		if jrp := jmc.RetryPolicy; jrp != nil {
+			var rp retryPolicy
+			var err error
+			if rp.initialBackoff, err = time.ParseDuration(jrp.InitialBackoff); err != nil {
+				return grpcConfig{}, errors.E(err, "methodConfig[%d]: bad initialBackoff", i)
+			}
+			if rp.maxBackoff, err = time.ParseDuration(jrp.MaxBackoff); err != nil {
+				return grpcConfig{}, errors.E(err, "methodConfig[%d]: bad maxBackoff", i)
+			}
+			if jrp.BackoffMultiplier <= 0 {
+				return grpcConfig{}, errors.E(nil, "methodConfig[%d]: backoffMultiplier must be positive, got %v", i, jrp.BackoffMultiplier)
+			}
+			rp.backoffMultiplier = jrp.BackoffMultiplier
+			for _, s := range jrp.RetryableStatusCodes {
+				c, ok := code.Code_value[s]
+				if !ok {
+					return grpcConfig{}, errors.E(nil, "methodConfig[%d]: unknown status code %q", i, s)
+				}
+				rp.codes = append(rp.codes, code.Code(c))
+			}
+			mc.retry = &rp
+		}
+
+		if len(jmc.Name) == 0 {
+			return grpcConfig{}, errors.E(nil, "methodConfig[%d]: need at least one name", i)
+		}
+		for _, n := range jmc.Name {
+			if n.Service == "" {
+				return grpcConfig{}, errors.E(nil, "methodConfig[%d]: name needs service", i)
+			}
+			ms := conf.methods[n.Service]
+			if ms == nil {
+				ms = map[string]*methodConfig{}
+				conf.methods[n.Service] = ms
+			}
+			if _, dup := ms[n.Method]; dup {
+				return grpcConfig{}, errors.E(nil, "methodConfig[%d]: duplicate config for %s/%s", i, n.Service, n.Method)
+			}
+			ms[n.Method] = &mc
+		}
+	}
+	return conf, nil
+}
+
+// methodConfig returns the config of the method, or nil if the method is not configured.
+// serv must be fully-qualified, without the leading dot.
+func (c grpcConfig) methodConfig(serv, meth string) *methodConfig {
+	ms := c.methods[serv]
+	if mc := ms[meth]; mc != nil {
+		return mc
+	}
+	return ms[""]
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genproto/googleapis/rpc/code"
+)
+
+func TestParseGRPCConfig(t *testing.T) {
+	conf, err := parseGRPCConfig(strings.NewReader(`{
+		"methodConfig": [
+			{
+				"name": [{"service": "my.pkg.Foo"}],
+				"timeout": "10s"
+			},
+			{
+				"name": [{"service": "my.pkg.Foo", "method": "Zip"}],
+				"timeout": "1.5s",
+				"retryPolicy": {
+					"maxAttempts": 5,
+					"initialBackoff": "0.1s",
+					"maxBackoff": "60s",
+					"backoffMultiplier": 1.3,
+					"retryableStatusCodes": ["UNAVAILABLE", "DEADLINE_EXCEEDED"]
+				}
+			}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tst := range []struct {
+		serv, meth string
+		want       *methodConfig
+	}{
+		{
+			serv: "my.pkg.Foo",
+			meth: "Zip",
+			want: &methodConfig{
+				timeout: 1500 * time.Millisecond,
+				retry: &retryPolicy{
+					initialBackoff:    100 * time.Millisecond,
+					maxBackoff:        time.Minute,
+					backoffMultiplier: 1.3,
+					codes:             []code.Code{code.Code_UNAVAILABLE, code.Code_DEADLINE_EXCEEDED},
+				},
+			},
+		},
+		{
+			serv: "my.pkg.Foo",
+			meth: "Zap",
+			want: &methodConfig{timeout: 10 * time.Second},
+		},
+		{
+			serv: "my.pkg.Bar",
+			meth: "Zip",
+		},
+	} {
+		got := conf.methodConfig(tst.serv, tst.meth)
+		if diff := cmp.Diff(got, tst.want, cmp.AllowUnexported(methodConfig{}, retryPolicy{})); diff != "" {
+			t.Errorf("methodConfig(%q, %q): (-got,+want)\n%s", tst.serv, tst.meth, diff)
+		}
+	}
+}
+
+func TestParseGRPCConfigError(t *testing.T) {
+	for _, in := range []string{
+		`not json`,
+		`{"methodConfig": [{"timeout": "1s"}]}`,
+		`{"methodConfig": [{"name": [{"method": "Zip"}]}]}`,
+		`{"methodConfig": [{"name": [{"service": "Foo"}], "timeout": "forever"}]}`,
+		`{"methodConfig": [{"name": [{"service": "Foo"}], "retryPolicy": {"initialBackoff": "1s", "maxBackoff": "2s", "backoffMultiplier": 2, "retryableStatusCodes": ["OOPS"]}}]}`,
+		`{"methodConfig": [{"name": [{"service": "Foo"}]}, {"name": [{"service": "Foo"}]}]}`,
+	} {
+		if _, err := parseGRPCConfig(strings.NewReader(in)); err == nil {
+			t.Errorf("parseGRPCConfig(%q): expected error", in)
+		}
+	}
+}
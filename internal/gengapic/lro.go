@@ -17,6 +17,7 @@ package gengapic
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
@@ -25,32 +26,35 @@ import (
 	"google.golang.org/genproto/googleapis/api/annotations"
 )
 
-func (g *generator) lroCall(servName string, m *descriptor.MethodDescriptorProto) error {
+func (g *generator) lroCall(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
 	inType := g.descInfo.Type[m.GetInputType()]
 	outType := g.descInfo.Type[m.GetOutputType()]
 
-	inSpec, err := g.descInfo.ImportSpec(inType)
+	inSpec, err := g.importSpec(inType)
 	if err != nil {
 		return err
 	}
 
-	outSpec, err := g.descInfo.ImportSpec(outType)
+	outSpec, err := g.importSpec(outType)
 	if err != nil {
 		return err
 	}
 
-	lroType := lroTypeName(*m.Name)
+	lroType := g.lroTypeName(m)
 	p := g.printf
 
-	p("func (c *%sClient) %s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) (*%s, error) {",
-		servName, *m.Name, inSpec.Name, inType.GetName(), lroType)
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) (*%s, error)",
+		g.clientMethodName(m), inSpec.Name, inType.GetName(), lroType))
 
-	g.insertMetadata()
+	if err := g.insertMetadata(m); err != nil {
+		return err
+	}
 	g.appendCallOpts(m)
+	g.callTimeout(serv, m, false)
 	p("  var resp *%s.%s", outSpec.Name, outType.GetName())
 	p("  err := gax.Invoke(ctx, func(ctx context.Context, settings gax.CallSettings) error {")
 	p("    var err error")
-	p("    resp, err = %s", grpcClientCall(servName, *m.Name))
+	p("    resp, err = %s", g.grpcClientCall(servName, *m.Name))
 	p("    return err")
 	p("  }, opts...)")
 	p("  if err != nil {")
@@ -70,58 +74,47 @@ func (g *generator) lroCall(servName string, m *descriptor.MethodDescriptorProto
 }
 
 func (g *generator) lroType(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
-	lroType := lroTypeName(*m.Name)
+	lroType := g.lroTypeName(m)
 	p := g.printf
 
 	eLRO, err := proto.GetExtension(m.Options, annotations.E_LongrunningOperationTypes)
 	if err != nil {
-		return errors.E(err, "cannot read LRO types")
+		return errors.At(g.pos(m), errors.User(err, "cannot read LRO types"), "method %s", m.GetName())
 	}
 	eLROType := eLRO.(*annotations.LongrunningOperationTypes)
 
+	// An empty response is not returned, and empty metadata is not exposed.
+	respEmpty := g.lroFullName(serv, eLROType.Response) == emptyType
 	var respType string
-	{
-		fullName := eLROType.Response
-
-		// eLRO.ResponseType is either fully-qualified or in the same package as the method.
-		if strings.IndexByte(fullName, '.') < 0 {
-			fullName = g.descInfo.ParentFile[serv].GetPackage() + "." + fullName
-		}
-
-		// When we build a map[name]Type in pbinfo, we prefix names with '.' to signify that they are fully qualified.
-		// The string in ResponseType does not have the prefix, so we add it.
-		fullName = "." + fullName
-
-		typ := g.descInfo.Type[fullName]
-		respSpec, err := g.descInfo.ImportSpec(typ)
+	if !respEmpty {
+		typ, respSpec, err := g.lroResultType(serv, eLROType.Response)
 		if err != nil {
-			return errors.E(err, "cannot find LRO type %q; type not linked?", fullName)
+			return err
 		}
 		g.imports[respSpec] = true
 		respType = fmt.Sprintf("%s.%s", respSpec.Name, typ.GetName())
 	}
 
-	hasMeta := eLROType.Metadata != ""
+	hasMeta := eLROType.Metadata != "" && g.lroFullName(serv, eLROType.Metadata) != emptyType
 	var metaType string
 	if hasMeta {
-		fullName := eLROType.Metadata
-		if strings.IndexByte(fullName, '.') < 0 {
-			fullName = g.descInfo.ParentFile[serv].GetPackage() + "." + fullName
-		}
-		fullName = "." + fullName
-
-		typ := g.descInfo.Type[fullName]
-		meta, err := g.descInfo.ImportSpec(typ)
+		typ, meta, err := g.lroResultType(serv, eLROType.Metadata)
 		if err != nil {
-			return errors.E(err, "cannot find LRO metadata type %q; type not linked?", fullName)
+			return err
 		}
 		g.imports[meta] = true
 		metaType = fmt.Sprintf("%s.%s", meta.Name, typ.GetName())
 	}
 
+	// Results of Wait and Poll, and what they return on error and success.
+	results, errRet, doneRet := fmt.Sprintf("(*%s, error)", respType), "nil, err", "resp, nil"
+	if respEmpty {
+		results, errRet, doneRet = "error", "err", "nil"
+	}
+
 	// Type definition
 	{
-		p("// %s manages a long-running operation from %s.", lroType, *m.Name)
+		p("// %s manages a long-running operation from %s.", lroType, g.clientMethodName(m))
 		p("type %s struct {", lroType)
 		p("  lro *longrunning.Operation")
 		p("}")
@@ -132,7 +125,7 @@ func (g *generator) lroType(servName string, serv *descriptor.ServiceDescriptorP
 	{
 		p("// %[1]s returns a new %[1]s from a given name.", lroType)
 		p("// The name must be that of a previously created %s, possibly from a different process.", lroType)
-		p("func (c *%sClient) %[2]s(name string) *%[2]s {", servName, lroType)
+		g.clientMethod(servName, fmt.Sprintf("%[1]s(name string) *%[1]s", lroType))
 		p("  return &%s{", lroType)
 		p("    lro: longrunning.InternalNewOperation(c.LROClient, &longrunningpb.Operation{Name: name}),")
 		p("  }")
@@ -144,15 +137,54 @@ func (g *generator) lroType(servName string, serv *descriptor.ServiceDescriptorP
 
 	// Wait
 	{
-		p("// Wait blocks until the long-running operation is completed, returning the response and any errors encountered.")
+		pc := g.gapicConf.pollingConfig(g.fullyQualifiedName(serv), m.GetName())
+
+		if respEmpty {
+			p("// Wait blocks until the long-running operation is completed, returning any error encountered.")
+		} else {
+			p("// Wait blocks until the long-running operation is completed, returning the response and any errors encountered.")
+		}
+		p("//")
+		if pc.totalTimeout > 0 {
+			p("// The operation is polled following the settings of %s, for at most %v;", m.GetName(), pc.totalTimeout)
+		} else {
+			p("// The operation is polled following the settings of %s;", m.GetName())
+		}
+		p("// use WaitWithBackoff to poll with other settings.")
 		p("//")
 		p("// See documentation of Poll for error-handling information.")
-		p("func (op *%s) Wait(ctx context.Context, opts ...gax.CallOption) (*%s, error) {", lroType, respType)
-		p("  var resp %s", respType)
-		p("  if err := op.lro.WaitWithInterval(ctx, &resp, time.Minute, opts...); err != nil {")
-		p("    return nil, err")
+		p("func (op *%s) Wait(ctx context.Context, opts ...gax.CallOption) %s {", lroType, results)
+		if pc.totalTimeout > 0 {
+			p("  ctx, cancel := context.WithTimeout(ctx, %d * time.Millisecond)", pc.totalTimeout/time.Millisecond)
+			p("  defer cancel()")
+		}
+		p("  return op.WaitWithBackoff(ctx, gax.Backoff{")
+		p("    Initial: %d * time.Millisecond,", pc.initialDelay/time.Millisecond)
+		p("    Max: %d * time.Millisecond,", pc.maxDelay/time.Millisecond)
+		p("    Multiplier: %.2f,", pc.multiplier)
+		p("  }, opts...)")
+		p("}")
+		p("")
+
+		p("// WaitWithBackoff is like Wait, but waits between polls following bo.")
+		p("// Polling stops when ctx is done; use context.WithTimeout to limit the total time spent waiting.")
+		p("func (op *%s) WaitWithBackoff(ctx context.Context, bo gax.Backoff, opts ...gax.CallOption) %s {", lroType, results)
+		p("  for {")
+		if respEmpty {
+			p("    if err := op.Poll(ctx, opts...); err != nil {")
+		} else {
+			p("    resp, err := op.Poll(ctx, opts...)")
+			p("    if err != nil {")
+		}
+		p("      return %s", errRet)
+		p("    }")
+		p("    if op.Done() {")
+		p("      return %s", doneRet)
+		p("    }")
+		p("    if err := gax.Sleep(ctx, bo.Pause()); err != nil {")
+		p("      return %s", errRet)
+		p("    }")
 		p("  }")
-		p("  return &resp, nil")
 		p("}")
 		p("")
 
@@ -169,20 +201,29 @@ func (g *generator) lroType(servName string, serv *descriptor.ServiceDescriptorP
 		}
 		p("// If Poll fails, the error is returned and op is unmodified. If Poll succeeds and")
 		p("// the operation has completed with failure, the error is returned and op.Done will return true.")
-		p("// If Poll succeeds and the operation has completed successfully,")
-		p("// op.Done will return true, and the response of the operation is returned.")
-		p("// If Poll succeeds and the operation has not completed, the returned response and error are both nil.")
-		p("func (op *%s) Poll(ctx context.Context, opts ...gax.CallOption) (*%s, error) {", lroType, respType)
-		p("  var resp %s", respType)
-		p("  if err := op.lro.Poll(ctx, &resp, opts...); err != nil {")
-		p("    return nil, err")
-		p("  }")
-		p("  if !op.Done() {")
-		p("    return nil, nil")
-		p("  }")
-		p("  return &resp, nil")
-		p("}")
-		p("")
+		if respEmpty {
+			p("// If Poll succeeds and the operation has completed successfully, or has not completed,")
+			p("// the returned error is nil; use op.Done to tell the two cases apart.")
+			p("func (op *%s) Poll(ctx context.Context, opts ...gax.CallOption) error {", lroType)
+			p("  return op.lro.Poll(ctx, nil, opts...)")
+			p("}")
+			p("")
+		} else {
+			p("// If Poll succeeds and the operation has completed successfully,")
+			p("// op.Done will return true, and the response of the operation is returned.")
+			p("// If Poll succeeds and the operation has not completed, the returned response and error are both nil.")
+			p("func (op *%s) Poll(ctx context.Context, opts ...gax.CallOption) (*%s, error) {", lroType, respType)
+			p("  var resp %s", respType)
+			p("  if err := op.lro.Poll(ctx, &resp, opts...); err != nil {")
+			p("    return nil, err")
+			p("  }")
+			p("  if !op.Done() {")
+			p("    return nil, nil")
+			p("  }")
+			p("  return &resp, nil")
+			p("}")
+			p("")
+		}
 	}
 
 	// Metadata
@@ -221,9 +262,68 @@ func (g *generator) lroType(servName string, serv *descriptor.ServiceDescriptorP
 		p("}")
 		p("")
 	}
+
+	// Cancel
+	{
+		p("// Cancel starts asynchronous cancellation on the long-running operation.")
+		p("// The server makes a best effort to cancel the operation, but success is not guaranteed.")
+		p("// Use Poll or Wait to check whether the cancellation succeeded or whether the operation completed despite cancellation.")
+		p("// On successful cancellation, the operation is not deleted; instead, Poll returns an error with code Canceled.")
+		p("func (op *%s) Cancel(ctx context.Context, opts ...gax.CallOption) error {", lroType)
+		p("  return op.lro.Cancel(ctx, opts...)")
+		p("}")
+		p("")
+	}
+
+	// Delete
+	{
+		p("// Delete deletes the long-running operation.")
+		p("// It indicates that the client is no longer interested in the result of the operation;")
+		p("// it does not cancel the operation.")
+		p("func (op *%s) Delete(ctx context.Context, opts ...gax.CallOption) error {", lroType)
+		p("  return op.lro.Delete(ctx, opts...)")
+		p("}")
+		p("")
+	}
 	return nil
 }
 
 func lroTypeName(methodName string) string {
 	return methodName + "Operation"
 }
+
+// lroFullName returns the fully-qualified name, with the leading dot, of name,
+// the response or metadata type in the longrunning.operation_types annotation of a method in serv.
+func (g *generator) lroFullName(serv *descriptor.ServiceDescriptorProto, name string) string {
+	// The name is either fully-qualified or in the same package as the method.
+	fullName := name
+	if strings.IndexByte(fullName, '.') < 0 {
+		fullName = g.descInfo.ParentFile[serv].GetPackage() + "." + fullName
+	}
+
+	// When we build a map[name]Type in pbinfo, we prefix names with '.' to signify that they are fully qualified.
+	// The string in the annotation does not have the prefix, so we add it.
+	return "." + strings.TrimPrefix(fullName, ".")
+}
+
+// lroResponseIsEmpty reports whether the response of LRO method m of serv is google.protobuf.Empty,
+// in which case its operation type returns no response.
+func (g *generator) lroResponseIsEmpty(serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) (bool, error) {
+	eLRO, err := proto.GetExtension(m.GetOptions(), annotations.E_LongrunningOperationTypes)
+	if err != nil {
+		return false, errors.At(g.pos(m), errors.User(err, "cannot read LRO types"), "method %s", m.GetName())
+	}
+	return g.lroFullName(serv, eLRO.(*annotations.LongrunningOperationTypes).Response) == emptyType, nil
+}
+
+// lroResultType resolves name, the response or metadata type in the
+// longrunning.operation_types annotation of a method in serv.
+func (g *generator) lroResultType(serv *descriptor.ServiceDescriptorProto, name string) (pbinfo.ProtoType, pbinfo.ImportSpec, error) {
+	fullName := g.lroFullName(serv, name)
+	typ := g.descInfo.Type[fullName]
+	spec, err := g.importSpec(typ)
+	if err != nil {
+		return nil, pbinfo.ImportSpec{}, errors.User(err, "cannot find LRO type %q; type not linked?", fullName)
+	}
+	return typ, spec, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestConfiguredPolling(t *testing.T) {
+	thing := &descriptor.DescriptorProto{Name: proto.String("Thing")}
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+
+	lroOpts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(lroOpts, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+		Response: "Thing",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	serv := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("Foo"),
+		Method: []*descriptor.MethodDescriptorProto{
+			{Name: proto.String("BuildThing"), InputType: proto.String(".my.pkg.Thing"), OutputType: proto.String(".google.longrunning.Operation"), Options: lroOpts},
+			{Name: proto.String("BuildThingSlowly"), InputType: proto.String(".my.pkg.Thing"), OutputType: proto.String(".google.longrunning.Operation"), Options: lroOpts},
+		},
+	}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	commonTypes(&g)
+	g.descInfo.Type[".my.pkg.Thing"] = thing
+	g.descInfo.ParentFile[thing] = file
+	g.descInfo.ParentFile[serv] = file
+
+	conf, err := parseGAPICConfig(strings.NewReader(`
+interfaces:
+- name: my.pkg.Foo
+  methods:
+  - name: BuildThing
+    long_running:
+      initial_poll_delay_millis: 100
+      poll_delay_multiplier: 2
+      max_poll_delay_millis: 1000
+  - name: BuildThingSlowly
+    long_running:
+      initial_poll_delay_millis: 5000
+      max_poll_delay_millis: 60000
+      total_poll_timeout_millis: 3600000
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.gapicConf = conf
+
+	for _, m := range serv.Method {
+		if err := g.lroType("Foo", serv, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	diff(t, "configured_polling", g.pt.String(), filepath.Join("testdata", "configured_polling.want"))
+}
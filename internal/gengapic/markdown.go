@@ -30,6 +30,11 @@ func MDPlain(s string) string {
 	for _, tok := range markdown.New().Parse([]byte(s)) {
 		mdr.plain(tok)
 	}
+	if len(mdr.unhandled) > 0 {
+		// The text of unhandled tokens is dropped; it is only a doc comment,
+		// so a warning is better than failing the generation.
+		log.Printf("warning: unhandled markdown in comment %q: %s", firstLine(s), strings.Join(mdr.unhandled, ", "))
+	}
 
 	return strings.TrimSpace(mdr.sb.String())
 }
@@ -42,6 +47,17 @@ type mdRenderer struct {
 	// Because the data structure is an array, it's technically possible for the links
 	// to nest, though I'm not sure if that'd be a valid Markdown.
 	linkTargets []string
+
+	// unhandled lists the types of the tokens that could not be rendered, without duplicates.
+	unhandled []string
+}
+
+// firstLine returns the first line of s, to identify a comment in warnings.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
 }
 
 func (m *mdRenderer) plain(t markdown.Token) {
@@ -57,10 +73,24 @@ func (m *mdRenderer) plain(t markdown.Token) {
 	case *markdown.Softbreak:
 		m.sb.WriteByte('\n')
 
-	case *markdown.ParagraphOpen:
-	case *markdown.ParagraphClose:
+	case *markdown.Hardbreak:
+		m.sb.WriteByte('\n')
+
+	case *markdown.ParagraphOpen, *markdown.HeadingOpen:
+	case *markdown.ParagraphClose, *markdown.HeadingClose:
 		m.sb.WriteString("\n\n")
 
+	// The items of lists are kept as paragraphs.
+	case *markdown.BulletListOpen, *markdown.BulletListClose,
+		*markdown.OrderedListOpen, *markdown.OrderedListClose,
+		*markdown.ListItemOpen, *markdown.ListItemClose:
+
+	// Code is indented, so that godoc shows it preformatted.
+	case *markdown.CodeBlock:
+		m.code(t.Content)
+	case *markdown.Fence:
+		m.code(t.Content)
+
 	case *markdown.LinkOpen:
 		m.linkTargets = append(m.linkTargets, t.Href)
 	case *markdown.LinkClose:
@@ -69,8 +99,20 @@ func (m *mdRenderer) plain(t markdown.Token) {
 		m.linkTargets = m.linkTargets[:l-1]
 
 	default:
-		// TODO(pongad): When going into production, we should turn this to warn.
-		// In the meantime, it's nice to crash to make sure we see it.
-		log.Panicf("unhandled type: %#v", t)
+		typ := fmt.Sprintf("%T", t)
+		for _, u := range m.unhandled {
+			if u == typ {
+				return
+			}
+		}
+		m.unhandled = append(m.unhandled, typ)
+	}
+}
+
+func (m *mdRenderer) code(s string) {
+	for _, l := range strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n") {
+		m.sb.WriteString("  ")
+		m.sb.WriteString(l)
 	}
+	m.sb.WriteString("\n\n")
 }
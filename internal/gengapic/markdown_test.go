@@ -17,7 +17,13 @@
 
 package gengapic
 
-import "testing"
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestMDPlain(t *testing.T) {
 	for _, tst := range []struct {
@@ -39,6 +45,23 @@ func TestMDPlain(t *testing.T) {
 			in:   "paragraph\n\nanother paragraph",
 			want: "paragraph\n\nanother paragraph",
 		},
+		{
+			// The items of lists are kept as paragraphs.
+			in:   "list:\n\n* one\n* two\n\n1. three",
+			want: "list:\n\none\n\ntwo\n\nthree",
+		},
+		{
+			in:   "code block:\n\n    a := 1\n    b := a\n\nafter",
+			want: "code block:\n\n  a := 1\n  b := a\n\nafter",
+		},
+		{
+			in:   "fence:\n\n```go\nf(x)\n```",
+			want: "fence:\n\n  f(x)",
+		},
+		{
+			in:   "# Heading\nline  \nbreak",
+			want: "Heading\n\nline\nbreak",
+		},
 	} {
 		got := MDPlain(tst.in)
 		if got != tst.want {
@@ -46,3 +69,24 @@ func TestMDPlain(t *testing.T) {
 		}
 	}
 }
+
+func TestMDPlainWarning(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	got := MDPlain("quotes:\n\n> one\n\n> two")
+	if want := "quotes:\n\none\n\ntwo"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	warn := buf.String()
+	if n := strings.Count(warn, "\n"); n != 1 {
+		t.Errorf("got %d warnings, want 1:\n%s", n, warn)
+	}
+	for _, s := range []string{`"quotes:"`, "*markdown.BlockquoteOpen, *markdown.BlockquoteClose"} {
+		if !strings.Contains(warn, s) {
+			t.Errorf("warning %q does not contain %q", warn, s)
+		}
+	}
+}
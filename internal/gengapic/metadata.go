@@ -0,0 +1,99 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+)
+
+// Kinds of generated methods, as reported in gapic_metadata.json.
+const (
+	unaryKind  = "unary"
+	pagedKind  = "paged"
+	lroKind    = "lro"
+	streamKind = "stream"
+)
+
+// gapicMetadata is the content of gapic_metadata.json,
+// which maps the RPCs of the generated services to the client methods generated for them.
+type gapicMetadata struct {
+	Schema         string `json:"schema"`
+	Comment        string `json:"comment"`
+	Language       string `json:"language"`
+	ProtoPackage   string `json:"protoPackage"`
+	LibraryPackage string `json:"libraryPackage"`
+
+	// Maps fully-qualified service names, without the leading dot, to their clients.
+	Services map[string]*serviceMetadata `json:"services"`
+}
+
+// serviceMetadata describes the client generated for a service.
+type serviceMetadata struct {
+	// Name of the Go client type.
+	Client string `json:"client"`
+
+	// Maps RPC names to the client methods generated for them.
+	RPCs map[string]rpcMetadata `json:"rpcs"`
+}
+
+// rpcMetadata describes the client method generated for an RPC.
+type rpcMetadata struct {
+	Method string `json:"method"`
+
+	// One of unaryKind, pagedKind, lroKind or streamKind.
+	Kind string `json:"kind"`
+}
+
+// addMetadata records that the method of kind kind was generated for m in the client of serv.
+func (g *generator) addMetadata(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, kind string) {
+	if g.metadata.Services == nil {
+		g.metadata.Services = map[string]*serviceMetadata{}
+	}
+	fullName := g.fullyQualifiedName(serv)
+	sm := g.metadata.Services[fullName]
+	if sm == nil {
+		sm = &serviceMetadata{
+			Client: servName + "Client",
+			RPCs:   map[string]rpcMetadata{},
+		}
+		g.metadata.Services[fullName] = sm
+	}
+	sm.RPCs[m.GetName()] = rpcMetadata{
+		Method: g.clientMethodName(m),
+		Kind:   kind,
+	}
+}
+
+// genMetadataFile generates gapic_metadata.json from the methods recorded by addMetadata.
+// protoPkg is the proto package of the services, and libPkg the import path of the generated package.
+func (g *generator) genMetadataFile(protoPkg, libPkg string) error {
+	g.metadata.Schema = "1.0"
+	g.metadata.Comment = "This file maps proto services/RPCs to the corresponding library clients/methods."
+	g.metadata.Language = "go"
+	g.metadata.ProtoPackage = protoPkg
+	g.metadata.LibraryPackage = libPkg
+
+	b, err := json.MarshalIndent(g.metadata, "", "  ")
+	if err != nil {
+		return errors.E(err, "cannot encode GAPIC metadata")
+	}
+	w := g.pt.Writer()
+	w.Write(b)
+	w.Write([]byte{'\n'})
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestGenMetadataFile(t *testing.T) {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+	types := map[string]*descriptor.DescriptorProto{
+		".my.pkg.Request":  {Name: proto.String("Request")},
+		".my.pkg.Response": {Name: proto.String("Response")},
+		".my.pkg.ListRequest": {
+			Name: proto.String("ListRequest"),
+			Field: []*descriptor.FieldDescriptorProto{
+				{Name: proto.String("page_size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT32), Label: optional},
+				{Name: proto.String("page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			},
+		},
+		".my.pkg.ListResponse": {
+			Name: proto.String("ListResponse"),
+			Field: []*descriptor.FieldDescriptorProto{
+				{Name: proto.String("next_page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+				{Name: proto.String("items"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED)},
+			},
+		},
+	}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	commonTypes(&g)
+	for name, typ := range types {
+		g.descInfo.Type[name] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+
+	lroOpts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(lroOpts, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+		Response: "Response",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	servs := []*descriptor.ServiceDescriptorProto{
+		{
+			Name: proto.String("Foo"),
+			Method: []*descriptor.MethodDescriptorProto{
+				{Name: proto.String("GetThing"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response")},
+				{Name: proto.String("DeleteThing"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(emptyType)},
+				{Name: proto.String("ListThings"), InputType: proto.String(".my.pkg.ListRequest"), OutputType: proto.String(".my.pkg.ListResponse")},
+				{Name: proto.String("CreateThing"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(lroType), Options: lroOpts},
+				{Name: proto.String("Close"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response")},
+			},
+		},
+		{
+			Name: proto.String("BarService"),
+			Method: []*descriptor.MethodDescriptorProto{
+				{Name: proto.String("Watch"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response"), ServerStreaming: proto.Bool(true)},
+				{Name: proto.String("Upload"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response"), ClientStreaming: proto.Bool(true)},
+				{Name: proto.String("Chat"), InputType: proto.String(".my.pkg.Request"), OutputType: proto.String(".my.pkg.Response"), ClientStreaming: proto.Bool(true), ServerStreaming: proto.Bool(true)},
+			},
+		},
+	}
+	for _, serv := range servs {
+		g.descInfo.ParentFile[serv] = file
+	}
+	if _, err := g.planNames(servs, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, serv := range servs {
+		servName := pbinfo.ReduceServName(serv.GetName(), "foo")
+		aux := auxTypes{iters: map[string]*descriptor.FieldDescriptorProto{}}
+		for _, m := range serv.GetMethod() {
+			if err := g.genMethod(servName, serv, m, &aux); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	g.reset()
+	if err := g.genMetadataFile("my.pkg", "path/to/foo"); err != nil {
+		t.Fatal(err)
+	}
+	diff(t, "gapic_metadata", g.pt.String(), filepath.Join("testdata", "gapic_metadata.want"))
+}
@@ -0,0 +1,544 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// mockTestErrCode is the code of the errors returned by the fake servers in error tests.
+// It is not retried by default, so the tests do not wait for backoffs.
+const mockTestErrCode = "codes.PermissionDenied"
+
+func mockServerName(serv *descriptor.ServiceDescriptorProto) string {
+	return "mock" + serv.GetName() + "Server"
+}
+
+func mockServerVar(serv *descriptor.ServiceDescriptorProto) string {
+	return "mock" + serv.GetName()
+}
+
+// genMockFile generates a fake server for each service in servs, and tests running
+// the generated clients against the fakes over a local connection.
+func (g *generator) genMockFile(servs []*descriptor.ServiceDescriptorProto, pkgName string) error {
+	for _, serv := range servs {
+		if err := g.mockServer(serv); err != nil {
+			return errors.At(g.pos(serv), err, "service %s", serv.GetName())
+		}
+	}
+	if err := g.mockTestMain(servs); err != nil {
+		return err
+	}
+	for _, serv := range servs {
+		servName := pbinfo.ReduceServName(serv.GetName(), pkgName)
+		for _, m := range serv.GetMethod() {
+			if err := g.mockTest(servName, serv, m, false); err != nil {
+				return errors.At(g.pos(m), err, "method %s.%s", serv.GetName(), m.GetName())
+			}
+			if err := g.mockTest(servName, serv, m, true); err != nil {
+				return errors.At(g.pos(m), err, "method %s.%s", serv.GetName(), m.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+// mockServer generates the fake server of serv.
+func (g *generator) mockServer(serv *descriptor.ServiceDescriptorProto) error {
+	p := g.printf
+
+	servSpec, err := g.importSpec(serv)
+	if err != nil {
+		return err
+	}
+	mock := mockServerName(serv)
+
+	p("// %s is a fake %s server. The queues of results are shared by the tests,", mock, serv.GetName())
+	p("// so the tests using it must not run in parallel.")
+	p("type %s struct {", mock)
+	p("  // Embed for forward compatibility.")
+	p("  // Tests will keep working if more methods are added in the future.")
+	p("  %s.%sServer", servSpec.Name, serv.GetName())
+	p("")
+	p("  // mu guards the fields below, which are accessed by the goroutines of the server.")
+	p("  mu sync.Mutex")
+	p("")
+	p("  // The requests received, in order.")
+	p("  reqs []proto.Message")
+	p("")
+	p("  // The results of the calls, in order.")
+	p("  // Each call takes the first error and, if it is nil, the first response.")
+	p("  errs  []error")
+	p("  resps []proto.Message")
+	p("}")
+	p("")
+
+	p("// setResults clears the requests received and queues the results of the next calls.")
+	p("func (s *%s) setResults(errs []error, resps []proto.Message) {", mock)
+	p("  s.mu.Lock()")
+	p("  defer s.mu.Unlock()")
+	p("  s.reqs = nil")
+	p("  s.errs = errs")
+	p("  s.resps = resps")
+	p("}")
+	p("")
+
+	p("// requests returns the requests received.")
+	p("func (s *%s) requests() []proto.Message {", mock)
+	p("  s.mu.Lock()")
+	p("  defer s.mu.Unlock()")
+	p("  return append([]proto.Message(nil), s.reqs...)")
+	p("}")
+	p("")
+
+	p("// record records req as received.")
+	p("func (s *%s) record(req proto.Message) {", mock)
+	p("  s.mu.Lock()")
+	p("  defer s.mu.Unlock()")
+	p("  s.reqs = append(s.reqs, req)")
+	p("}")
+	p("")
+
+	p("// result removes the result of the next call from the queues.")
+	p("func (s *%s) result() (proto.Message, error) {", mock)
+	p("  s.mu.Lock()")
+	p("  defer s.mu.Unlock()")
+	p("  if len(s.errs) > 0 {")
+	p("    err := s.errs[0]")
+	p("    s.errs = s.errs[1:]")
+	p("    if err != nil {")
+	p("      return nil, err")
+	p("    }")
+	p("  }")
+	p("  if len(s.resps) == 0 {")
+	p(`    return nil, gstatus.Error(codes.Internal, "no response queued")`)
+	p("  }")
+	p("  resp := s.resps[0]")
+	p("  s.resps = s.resps[1:]")
+	p("  return resp, nil")
+	p("}")
+	p("")
+
+	g.imports[servSpec] = true
+	g.imports[pbinfo.ImportSpec{Path: "github.com/golang/protobuf/proto"}] = true
+	g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc/codes"}] = true
+	g.imports[pbinfo.ImportSpec{Name: "gstatus", Path: "google.golang.org/grpc/status"}] = true
+	g.imports[pbinfo.ImportSpec{Path: "sync"}] = true
+
+	for _, m := range serv.GetMethod() {
+		inType := g.descInfo.Type[m.GetInputType()]
+		inSpec, err := g.importSpec(inType)
+		if err != nil {
+			return err
+		}
+		outType := g.descInfo.Type[m.GetOutputType()]
+		outSpec, err := g.importSpec(outType)
+		if err != nil {
+			return err
+		}
+		g.imports[inSpec] = true
+		g.imports[outSpec] = true
+
+		streamType := servSpec.Name + "." + serv.GetName() + "_" + m.GetName() + "Server"
+		switch {
+		case m.GetClientStreaming():
+			p("func (s *%s) %s(stream %s) error {", mock, m.GetName(), streamType)
+			p("  if err := checkClientInfo(stream.Context()); err != nil {")
+			p("    return err")
+			p("  }")
+			p("  for {")
+			p("    req, err := stream.Recv()")
+			p("    if err == io.EOF {")
+			p("      break")
+			p("    }")
+			p("    if err != nil {")
+			p("      return err")
+			p("    }")
+			p("    s.record(req)")
+			p("  }")
+			p("  resp, err := s.result()")
+			p("  if err != nil {")
+			p("    return err")
+			p("  }")
+			if m.GetServerStreaming() {
+				p("  return stream.Send(resp.(*%s.%s))", outSpec.Name, outType.GetName())
+			} else {
+				p("  return stream.SendAndClose(resp.(*%s.%s))", outSpec.Name, outType.GetName())
+			}
+			p("}")
+			p("")
+			g.imports[pbinfo.ImportSpec{Path: "io"}] = true
+
+		case m.GetServerStreaming():
+			p("func (s *%s) %s(req *%s.%s, stream %s) error {", mock, m.GetName(), inSpec.Name, inType.GetName(), streamType)
+			p("  if err := checkClientInfo(stream.Context()); err != nil {")
+			p("    return err")
+			p("  }")
+			p("  s.record(req)")
+			p("  resp, err := s.result()")
+			p("  if err != nil {")
+			p("    return err")
+			p("  }")
+			p("  return stream.Send(resp.(*%s.%s))", outSpec.Name, outType.GetName())
+			p("}")
+			p("")
+
+		default:
+			p("func (s *%s) %s(ctx context.Context, req *%s.%s) (*%s.%s, error) {",
+				mock, m.GetName(), inSpec.Name, inType.GetName(), outSpec.Name, outType.GetName())
+			p("  if err := checkClientInfo(ctx); err != nil {")
+			p("    return nil, err")
+			p("  }")
+			p("  s.record(req)")
+			p("  resp, err := s.result()")
+			p("  if err != nil {")
+			p("    return nil, err")
+			p("  }")
+			p("  return resp.(*%s.%s), nil", outSpec.Name, outType.GetName())
+			p("}")
+			p("")
+			g.imports[pbinfo.ImportSpec{Path: "golang.org/x/net/context"}] = true
+		}
+	}
+	return nil
+}
+
+// mockTestMain generates the shared test setup, serving the fakes of servs.
+func (g *generator) mockTestMain(servs []*descriptor.ServiceDescriptorProto) error {
+	p := g.printf
+
+	p("// checkClientInfo reports an error if the metadata in ctx does not identify the client library.")
+	p("func checkClientInfo(ctx context.Context) error {")
+	p("  md, _ := metadata.FromIncomingContext(ctx)")
+	p(`  if xg := md["x-goog-api-client"]; len(xg) == 0 || !strings.Contains(xg[0], "gl-go/") {`)
+	p(`    return fmt.Errorf("x-goog-api-client = %%v, expected gl-go key", xg)`)
+	p("  }")
+	p("  return nil")
+	p("}")
+	p("")
+
+	p("// clientOpt connects the clients to the fake servers.")
+	p("var clientOpt option.ClientOption")
+	p("")
+	for _, serv := range servs {
+		p("var %s %s", mockServerVar(serv), mockServerName(serv))
+	}
+	p("")
+
+	p("func TestMain(m *testing.M) {")
+	p("  serv := grpc.NewServer()")
+	for _, serv := range servs {
+		servSpec, err := g.importSpec(serv)
+		if err != nil {
+			return err
+		}
+		p("  %s.Register%sServer(serv, &%s)", servSpec.Name, serv.GetName(), mockServerVar(serv))
+	}
+	p("")
+	p(`  lis, err := net.Listen("tcp", "localhost:0")`)
+	p("  if err != nil {")
+	p("    log.Fatal(err)")
+	p("  }")
+	p("  go serv.Serve(lis)")
+	p("")
+	p("  conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())")
+	p("  if err != nil {")
+	p("    log.Fatal(err)")
+	p("  }")
+	p("  clientOpt = option.WithGRPCConn(conn)")
+	p("")
+	p("  os.Exit(m.Run())")
+	p("}")
+	p("")
+
+	for _, imp := range []pbinfo.ImportSpec{
+		{Path: "fmt"},
+		{Path: "log"},
+		{Path: "net"},
+		{Path: "os"},
+		{Path: "strings"},
+		{Path: "testing"},
+		{Path: "golang.org/x/net/context"},
+		{Path: "google.golang.org/api/option"},
+		{Path: "google.golang.org/grpc"},
+		{Path: "google.golang.org/grpc/metadata"},
+	} {
+		g.imports[imp] = true
+	}
+	return nil
+}
+
+// mockTest generates a test calling method m of the client of serv.
+// If isErr, the fake server fails the call and the test checks the error code.
+func (g *generator) mockTest(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, isErr bool) error {
+	p := g.printf
+
+	inType := g.descInfo.Type[m.GetInputType()]
+	inSpec, err := g.importSpec(inType)
+	if err != nil {
+		return err
+	}
+	outType := g.descInfo.Type[m.GetOutputType()]
+	outSpec, err := g.importSpec(outType)
+	if err != nil {
+		return err
+	}
+	g.imports[inSpec] = true
+
+	// Dispatch in the same order as genMethod.
+	isLRO := m.GetOutputType() == lroType
+	isEmpty := !isLRO && m.GetOutputType() == emptyType
+	// An LRO with an empty response is waited for like an empty method is called.
+	if isLRO {
+		if isEmpty, err = g.lroResponseIsEmpty(serv, m); err != nil {
+			return err
+		}
+	}
+	var pi *pagingInfo
+	var iter iterType
+	if !isLRO && !isEmpty {
+		if pi, err = g.pagingInfoOf(serv, m); err != nil {
+			return err
+		}
+	}
+	if pi != nil {
+		if iter, err = g.iterTypeOf(pi.elemField); err != nil {
+			return err
+		}
+	}
+	mock := mockServerVar(serv)
+
+	suffix := ""
+	if isErr {
+		suffix = "Error"
+	}
+	p("func Test%s%s%s(t *testing.T) {", serv.GetName(), m.GetName(), suffix)
+
+	// Queue the result.
+	var respType string
+	switch {
+	case isLRO && isEmpty:
+		respType = "emptypb.Empty"
+		g.imports[pbinfo.ImportSpec{Name: "emptypb", Path: "github.com/golang/protobuf/ptypes/empty"}] = true
+	case isLRO:
+		eLRO, err := proto.GetExtension(m.GetOptions(), annotations.E_LongrunningOperationTypes)
+		if err != nil {
+			return errors.User(err, "cannot read LRO types")
+		}
+		typ, spec, err := g.lroResultType(serv, eLRO.(*annotations.LongrunningOperationTypes).Response)
+		if err != nil {
+			return err
+		}
+		respType = spec.Name + "." + typ.GetName()
+		g.imports[spec] = true
+	default:
+		respType = outSpec.Name + "." + outType.GetName()
+		g.imports[outSpec] = true
+	}
+
+	var elemField string
+	if !isErr {
+		if pi != nil {
+			for _, imp := range iter.elemImports {
+				g.imports[imp] = true
+			}
+			elemField = naming.CamelCase(pi.elemField.GetName())
+			p("  var expectedResponse = &%s{", respType)
+			p(`    %s: "",`, naming.CamelCase(pi.nextTokenField.GetName()))
+			if iter.keyTypeName != "" {
+				p("    %s: map[%s]%s{%s: %s},", elemField, iter.keyTypeName, iter.valueTypeName,
+					zeroValue(iter.keyTypeName), zeroValue(iter.valueTypeName))
+			} else {
+				p("    %s: []%s{%s},", elemField, iter.elemTypeName, zeroValue(iter.elemTypeName))
+			}
+			p("  }")
+		} else {
+			p("  var expectedResponse = &%s{}", respType)
+		}
+		p("")
+	} else {
+		p("  errCode := %s", mockTestErrCode)
+	}
+
+	switch {
+	case isLRO && isErr:
+		p("  %s.setResults(nil, []proto.Message{&longrunningpb.Operation{", mock)
+		p(`    Name: "longrunning-test",`)
+		p("    Done: true,")
+		p("    Result: &longrunningpb.Operation_Error{")
+		p("      Error: &status.Status{")
+		p("        Code:    int32(errCode),")
+		p(`        Message: "test error",`)
+		p("      },")
+		p("    },")
+		p("  }})")
+		g.imports[pbinfo.ImportSpec{Name: "longrunningpb", Path: "google.golang.org/genproto/googleapis/longrunning"}] = true
+		g.imports[pbinfo.ImportSpec{Name: "status", Path: "google.golang.org/genproto/googleapis/rpc/status"}] = true
+	case isLRO:
+		p("  anyResp, err := ptypes.MarshalAny(expectedResponse)")
+		p("  if err != nil {")
+		p("    t.Fatal(err)")
+		p("  }")
+		p("  %s.setResults(nil, []proto.Message{&longrunningpb.Operation{", mock)
+		p(`    Name:   "longrunning-test",`)
+		p("    Done:   true,")
+		p("    Result: &longrunningpb.Operation_Response{Response: anyResp},")
+		p("  }})")
+		g.imports[pbinfo.ImportSpec{Name: "longrunningpb", Path: "google.golang.org/genproto/googleapis/longrunning"}] = true
+		g.imports[pbinfo.ImportSpec{Path: "github.com/golang/protobuf/ptypes"}] = true
+	case isErr:
+		p(`  %s.setResults([]error{gstatus.Error(errCode, "test error")}, nil)`, mock)
+		g.imports[pbinfo.ImportSpec{Name: "gstatus", Path: "google.golang.org/grpc/status"}] = true
+	default:
+		p("  %s.setResults(nil, []proto.Message{expectedResponse})", mock)
+	}
+	p("")
+
+	p("  var request = &%s.%s{}", inSpec.Name, inType.GetName())
+	p("")
+	p("  c, err := New%sClient(context.Background(), clientOpt)", servName)
+	p("  if err != nil {")
+	p("    t.Fatal(err)")
+	p("  }")
+	p("")
+
+	// Make the call.
+	fatal := "t.Fatal(err)"
+	if isErr {
+		fatal = "t.Fatalf(\"got error %v, want error code %v\", err, errCode)"
+	}
+	switch {
+	case isLRO:
+		p("  respLRO, err := c.%s(context.Background(), request)", g.clientMethodName(m))
+		p("  if err != nil {")
+		p("    t.Fatal(err)")
+		p("  }")
+		if isEmpty {
+			p("  err = respLRO.Wait(context.Background())")
+		} else {
+			p("  resp, err := respLRO.Wait(context.Background())")
+		}
+	case isEmpty:
+		p("  err = c.%s(context.Background(), request)", g.clientMethodName(m))
+	case pi != nil:
+		p("  resp, err := c.%s(context.Background(), request).Next()", g.clientMethodName(m))
+	case m.GetClientStreaming():
+		p("  stream, err := c.%s(context.Background())", g.clientMethodName(m))
+		p("  if err != nil {")
+		p("    t.Fatal(err)")
+		p("  }")
+		p("  if err := stream.Send(request); err != nil && err != io.EOF {")
+		p("    t.Fatal(err)")
+		p("  }")
+		if m.GetServerStreaming() {
+			p("  if err := stream.CloseSend(); err != nil {")
+			p("    t.Fatal(err)")
+			p("  }")
+			p("  resp, err := stream.Recv()")
+		} else {
+			p("  resp, err := stream.CloseAndRecv()")
+		}
+		g.imports[pbinfo.ImportSpec{Path: "io"}] = true
+	case m.GetServerStreaming():
+		p("  stream, err := c.%s(context.Background(), request)", g.clientMethodName(m))
+		p("  if err != nil {")
+		p("    t.Fatal(err)")
+		p("  }")
+		p("  resp, err := stream.Recv()")
+	default:
+		p("  resp, err := c.%s(context.Background(), request)", g.clientMethodName(m))
+	}
+
+	// Check the result.
+	if isErr {
+		p("  if st, ok := gstatus.FromError(err); !ok {")
+		p("    %s", fatal)
+		p("  } else if c := st.Code(); c != errCode {")
+		p("    %s", fatal)
+		p("  }")
+		if !isEmpty {
+			p("  _ = resp")
+		}
+		p("}")
+		p("")
+		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc/codes"}] = true
+		return nil
+	}
+
+	p("  if err != nil {")
+	p("    t.Fatal(err)")
+	p("  }")
+	p("")
+	p("  if want, got := request, %s.requests()[0]; !proto.Equal(want, got) {", mock)
+	p(`    t.Errorf("wrong request %%q, want %%q", got, want)`)
+	p("  }")
+	switch {
+	case isEmpty:
+	case pi != nil:
+		// For maps, compare the value of the only entry.
+		valueField := pi.elemField
+		p("")
+		if iter.keyTypeName != "" {
+			valueField = findField(mapEntry(g.descInfo.Type[pi.elemField.GetTypeName()]), "value")
+			p("  want := expectedResponse.%s[%s]", elemField, zeroValue(iter.keyTypeName))
+			p("  got := resp.Value")
+		} else {
+			p("  want := expectedResponse.%s[0]", elemField)
+			p("  got := resp")
+		}
+		switch {
+		case valueField.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+			p("  if !proto.Equal(want, got) {")
+		case valueField.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES:
+			p("  if !bytes.Equal(want, got) {")
+			g.imports[pbinfo.ImportSpec{Path: "bytes"}] = true
+		default:
+			p("  if want != got {")
+		}
+		p(`    t.Errorf("wrong response %%v, want %%v", got, want)`)
+		p("  }")
+	default:
+		p("")
+		p("  if want, got := expectedResponse, resp; !proto.Equal(want, got) {")
+		p(`    t.Errorf("wrong response %%q, want %%q", got, want)`)
+		p("  }")
+	}
+	p("}")
+	p("")
+	return nil
+}
+
+// zeroValue returns a Go expression for the zero value of typ,
+// using a pointer to an empty message for message types.
+func zeroValue(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "*"):
+		return "&" + typ[1:] + "{}"
+	case typ == "string":
+		return `""`
+	case typ == "bool":
+		return "false"
+	case typ == "[]byte":
+		return "nil"
+	}
+	return "0"
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestGenMockFile(t *testing.T) {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+
+	inputType := &descriptor.DescriptorProto{
+		Name: proto.String("InputType"),
+	}
+	outputType := &descriptor.DescriptorProto{
+		Name: proto.String("OutputType"),
+	}
+	pageInputType := &descriptor.DescriptorProto{
+		Name: proto.String("PageInputType"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("page_size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT32), Label: optional},
+			{Name: proto.String("page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+		},
+	}
+	pageOutputType := &descriptor.DescriptorProto{
+		Name: proto.String("PageOutputType"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("next_page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{
+				Name:     proto.String("items"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String(".my.pkg.OutputType"),
+				Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		},
+	}
+
+	itemsEntry := &descriptor.DescriptorProto{
+		Name: proto.String("ItemsEntry"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("key"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{Name: proto.String("value"), Type: typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE), TypeName: proto.String(".my.pkg.OutputType"), Label: optional},
+		},
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	mapPageOutputType := &descriptor.DescriptorProto{
+		Name: proto.String("MapPageOutputType"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("next_page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{
+				Name:     proto.String("items"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String(".my.pkg.MapPageOutputType.ItemsEntry"),
+				Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		},
+		NestedType: []*descriptor.DescriptorProto{itemsEntry},
+	}
+
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+
+	commonTypes(&g)
+	for _, typ := range []*descriptor.DescriptorProto{
+		inputType, outputType, pageInputType, pageOutputType, mapPageOutputType,
+	} {
+		g.descInfo.Type[".my.pkg."+typ.GetName()] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+	g.descInfo.Type[".my.pkg.MapPageOutputType.ItemsEntry"] = itemsEntry
+
+	lroOpts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(lroOpts, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+		Response: "OutputType",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	emptyLROOpts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(emptyLROOpts, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+		Response: "google.protobuf.Empty",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	serv := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("FooService"),
+		Method: []*descriptor.MethodDescriptorProto{
+			{Name: proto.String("GetOneThing"), InputType: proto.String(".my.pkg.InputType"), OutputType: proto.String(".my.pkg.OutputType")},
+			{Name: proto.String("GetEmptyThing"), InputType: proto.String(".my.pkg.InputType"), OutputType: proto.String(emptyType)},
+			{Name: proto.String("GetManyThings"), InputType: proto.String(".my.pkg.PageInputType"), OutputType: proto.String(".my.pkg.PageOutputType")},
+			{Name: proto.String("GetManyMappedThings"), InputType: proto.String(".my.pkg.PageInputType"), OutputType: proto.String(".my.pkg.MapPageOutputType")},
+			{Name: proto.String("GetBigThing"), InputType: proto.String(".my.pkg.InputType"), OutputType: proto.String(lroType), Options: lroOpts},
+			{Name: proto.String("DeleteBigThing"), InputType: proto.String(".my.pkg.InputType"), OutputType: proto.String(lroType), Options: emptyLROOpts},
+			{Name: proto.String("ServerThings"), InputType: proto.String(".my.pkg.InputType"), OutputType: proto.String(".my.pkg.OutputType"), ServerStreaming: proto.Bool(true)},
+			{Name: proto.String("ClientThings"), InputType: proto.String(".my.pkg.InputType"), OutputType: proto.String(".my.pkg.OutputType"), ClientStreaming: proto.Bool(true)},
+			{
+				Name:            proto.String("BidiThings"),
+				InputType:       proto.String(".my.pkg.InputType"),
+				OutputType:      proto.String(".my.pkg.OutputType"),
+				ClientStreaming: proto.Bool(true),
+				ServerStreaming: proto.Bool(true),
+			},
+		},
+	}
+	g.descInfo.ParentFile[serv] = file
+
+	if err := g.genMockFile([]*descriptor.ServiceDescriptorProto{serv}, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	diff(t, "mock", g.pt.String(), filepath.Join("testdata", "mock.want"))
+}
+
+func TestGenMockRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	// The generated tests run the client against the fake server.
+	goTestPackage(t, genTestPackage(t, "grpc"))
+}
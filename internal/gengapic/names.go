@@ -0,0 +1,337 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+)
+
+// names holds the names of the identifiers generated for the services of the package,
+// once collisions with each other and with the identifiers the generator declares are resolved.
+// It is filled by planNames before any file is generated.
+// Without a plan, for example when generating a single method in tests, the default names are used.
+type names struct {
+	// Field of the client holding the gRPC client, by reduced service name.
+	grpcFields map[string]string
+
+	// Names derived from each method: the client method, the operation type of an LRO method,
+	// the stream type of a client streaming method, and the pages method, page type
+	// and page iterator type of a paging method.
+	methods, lros, streams, pagesMethods, pages, pageIters map[*descriptor.MethodDescriptorProto]string
+
+	// Iterator and pair type names, by iterKey.
+	iters map[string]iterNames
+
+	// Exported members of the client of each service, for the flattened methods to avoid.
+	members map[*descriptor.ServiceDescriptorProto]map[string]bool
+
+	// Descriptions of the renames, in the order they were made.
+	renames []string
+}
+
+// iterNames are the names of the types generated to iterate over an element type.
+type iterNames struct {
+	// The pair type is only set for maps.
+	iter, pair string
+}
+
+// pkgReservedNames are declared by the generator once per package.
+var pkgReservedNames = []string{"DefaultAuthScopes", "insertMetadata"}
+
+// planNames chooses the names of the identifiers generated for servs, the services of the package.
+//
+// Names are claimed in the order the services and methods are declared: first the names of the
+// clients, then the client methods, then the types and methods derived from each method.
+// A client method or derived name already taken is renamed by appending underscores until it is free,
+// the way protoc-gen-go renames conflicting fields. Each rename is recorded in g.names.renames.
+// Clients that would be named the same cannot be renamed: the services declared last are reported as errors
+// and left out of the returned services, the ones to generate.
+// The methods that cannot be planned are reported too, wrapped like the errors of gen,
+// and keep their default names.
+func (g *generator) planNames(servs []*descriptor.ServiceDescriptorProto, pkgName string) ([]*descriptor.ServiceDescriptorProto, error) {
+	n := names{
+		grpcFields:   map[string]string{},
+		methods:      map[*descriptor.MethodDescriptorProto]string{},
+		lros:         map[*descriptor.MethodDescriptorProto]string{},
+		streams:      map[*descriptor.MethodDescriptorProto]string{},
+		pagesMethods: map[*descriptor.MethodDescriptorProto]string{},
+		pages:        map[*descriptor.MethodDescriptorProto]string{},
+		pageIters:    map[*descriptor.MethodDescriptorProto]string{},
+		iters:        map[string]iterNames{},
+		members:      map[*descriptor.ServiceDescriptorProto]map[string]bool{},
+	}
+
+	var errs errors.List
+
+	// Package-level identifiers.
+	decls := map[string]bool{}
+	for _, name := range pkgReservedNames {
+		decls[name] = true
+	}
+	var okServs []*descriptor.ServiceDescriptorProto
+	for _, serv := range servs {
+		servName := pbinfo.ReduceServName(serv.GetName(), pkgName)
+		clientNames := []string{
+			servName + "Client",
+			servName + "CallOptions",
+			"New" + servName + "Client",
+			"default" + servName + "ClientOptions",
+			"default" + servName + "CallOptions",
+		}
+		if g.opts.clientInterface {
+			clientNames = append(clientNames, servName+"ClientAPI")
+		}
+		if g.opts.hasTransport(restTransport) {
+			clientNames = append(clientNames,
+				restClientName(servName),
+				"New"+servName+"RESTClient",
+				"default"+servName+"RESTClientOptions")
+		}
+		conflict := ""
+		for _, name := range clientNames {
+			if decls[name] {
+				conflict = name
+				break
+			}
+		}
+		if conflict != "" {
+			errs.Add(errors.At(g.pos(serv), errors.User(nil, "%s is already declared by another service of the package", conflict), "service %s", serv.GetName()))
+			continue
+		}
+		for _, name := range clientNames {
+			decls[name] = true
+		}
+		okServs = append(okServs, serv)
+
+		if servName == "" {
+			// The field would be "client"; name it after the service instead.
+			field := lowerFirst(serv.GetName() + "Client")
+			n.grpcFields[servName] = field
+			n.rename(fmt.Sprintf("service %s: gRPC client field", serv.GetName()), grpcClientField(servName), field)
+		}
+	}
+
+	// Client methods, which keep their names unless they collide with the client's own members.
+	for _, serv := range okServs {
+		members := map[string]bool{"Close": true, "Connection": true, "CallOptions": true}
+		for _, m := range serv.GetMethod() {
+			if m.GetOutputType() == lroType {
+				members["LROClient"] = true
+			}
+		}
+		for _, m := range serv.GetMethod() {
+			n.methods[m] = n.claim(fmt.Sprintf("method %s.%s: client method", serv.GetName(), m.GetName()), m.GetName(), members)
+		}
+		n.members[serv] = members
+	}
+
+	// Types and methods derived from the methods.
+	for _, serv := range okServs {
+		members := n.members[serv]
+		for _, m := range serv.GetMethod() {
+			what := fmt.Sprintf("method %s.%s: ", serv.GetName(), m.GetName())
+
+			if m.GetOutputType() == lroType {
+				// The operation type and the client method creating it from a name share the name.
+				n.lros[m] = n.claim(what+"operation type", lroTypeName(m.GetName()), decls, members)
+				continue
+			}
+			if m.GetClientStreaming() && !m.GetServerStreaming() {
+				n.streams[m] = n.claim(what+"stream type", m.GetName()+"Stream", decls)
+				continue
+			}
+
+			// gen reports the same errors again; they are listed once.
+			methodErr := func(err error) error {
+				return errors.At(g.pos(serv), errors.At(g.pos(m), err, "method %s", m.GetName()), "service %s", serv.GetName())
+			}
+
+			pi, err := g.pagingInfoOf(serv, m)
+			if err != nil {
+				errs.Add(methodErr(err))
+				continue
+			}
+			if pi == nil {
+				continue
+			}
+			n.pagesMethods[m] = n.claim(what+"pages method", m.GetName()+"Pages", members)
+			n.pages[m] = n.claim(what+"page type", m.GetName()+"Page", decls)
+			n.pageIters[m] = n.claim(what+"page iterator type", m.GetName()+"PageIterator", decls)
+
+			key := g.iterKey(pi.elemField)
+			if _, ok := n.iters[key]; ok {
+				continue
+			}
+			iter, err := g.defaultIterTypeOf(pi.elemField)
+			if err != nil {
+				errs.Add(methodErr(err))
+				continue
+			}
+			var in iterNames
+			if iter.keyTypeName != "" {
+				in.pair = n.claim(what+"pair type", iter.elemTypeName, decls)
+			}
+			in.iter = n.claim(what+"iterator type", iter.iterTypeName, decls)
+			n.iters[key] = in
+		}
+	}
+
+	// iterTypeOf allocated import names; the files allocate their own.
+	g.reset()
+	g.names = n
+	return okServs, errs.Err(0)
+}
+
+// claim returns name, with underscores appended until it is not taken in any of namespaces,
+// and marks it taken in all of them. If name is changed, the rename is recorded.
+func (n *names) claim(what, name string, namespaces ...map[string]bool) string {
+	taken := func(s string) bool {
+		for _, ns := range namespaces {
+			if ns[s] {
+				return true
+			}
+		}
+		return false
+	}
+
+	got := name
+	for taken(got) {
+		got += "_"
+	}
+	for _, ns := range namespaces {
+		ns[got] = true
+	}
+	if got != name {
+		n.rename(what, name, got)
+	}
+	return got
+}
+
+func (n *names) rename(what, from, to string) {
+	n.renames = append(n.renames, fmt.Sprintf("%s %s renamed to %s to avoid a collision", what, from, to))
+}
+
+// iterKey identifies the element type iterated over in elemField.
+// Fields with the same key are iterated over with the same iterator.
+func (g *generator) iterKey(elemField *descriptor.FieldDescriptorProto) string {
+	fieldKey := func(f *descriptor.FieldDescriptorProto) string {
+		if f.GetTypeName() != "" {
+			return f.GetTypeName()
+		}
+		return f.GetType().String()
+	}
+	if entry := mapEntry(g.descInfo.Type[elemField.GetTypeName()]); entry != nil {
+		return fmt.Sprintf("map<%s,%s>", fieldKey(findField(entry, "key")), fieldKey(findField(entry, "value")))
+	}
+	return fieldKey(elemField)
+}
+
+// clientMembers returns the exported members of the client of serv, excluding flattened methods.
+// The caller may modify the returned map.
+func (g *generator) clientMembers(serv *descriptor.ServiceDescriptorProto) (map[string]bool, error) {
+	members := map[string]bool{}
+	if planned, ok := g.names.members[serv]; ok {
+		for name := range planned {
+			members[name] = true
+		}
+		return members, nil
+	}
+
+	members["Close"] = true
+	members["Connection"] = true
+	members["CallOptions"] = true
+	for _, m := range serv.GetMethod() {
+		members[g.clientMethodName(m)] = true
+		if m.GetOutputType() == lroType {
+			members["LROClient"] = true
+			members[g.lroTypeName(m)] = true
+			continue
+		}
+		pi, err := g.pagingInfoOf(serv, m)
+		if err != nil {
+			return nil, errors.At(g.pos(m), err, "method %s", m.GetName())
+		}
+		if pi != nil {
+			members[g.pagesMethodName(m)] = true
+		}
+	}
+	return members, nil
+}
+
+// clientMethodName returns the name of the client method generated for m.
+func (g *generator) clientMethodName(m *descriptor.MethodDescriptorProto) string {
+	if name, ok := g.names.methods[m]; ok {
+		return name
+	}
+	return m.GetName()
+}
+
+// lroTypeName returns the name of the operation type of LRO method m.
+// The client method creating an operation from its name has the same name.
+func (g *generator) lroTypeName(m *descriptor.MethodDescriptorProto) string {
+	if name, ok := g.names.lros[m]; ok {
+		return name
+	}
+	return lroTypeName(m.GetName())
+}
+
+// clientStreamTypeName returns the name of the type wrapping the stream of client streaming method m.
+func (g *generator) clientStreamTypeName(m *descriptor.MethodDescriptorProto) string {
+	if name, ok := g.names.streams[m]; ok {
+		return name
+	}
+	return m.GetName() + "Stream"
+}
+
+// pagesMethodName returns the name of the client method iterating over pages of results of paging method m.
+func (g *generator) pagesMethodName(m *descriptor.MethodDescriptorProto) string {
+	if name, ok := g.names.pagesMethods[m]; ok {
+		return name
+	}
+	return m.GetName() + "Pages"
+}
+
+// pageTypeName returns the name of the type holding a page of results of paging method m.
+func (g *generator) pageTypeName(m *descriptor.MethodDescriptorProto) string {
+	if name, ok := g.names.pages[m]; ok {
+		return name
+	}
+	return m.GetName() + "Page"
+}
+
+// pageIterTypeName returns the name of the iterator over pages of results of paging method m.
+func (g *generator) pageIterTypeName(m *descriptor.MethodDescriptorProto) string {
+	if name, ok := g.names.pageIters[m]; ok {
+		return name
+	}
+	return m.GetName() + "PageIterator"
+}
+
+// grpcClientField returns the name of the field of the client of service servName holding the gRPC client.
+func (g *generator) grpcClientField(servName string) string {
+	if name, ok := g.names.grpcFields[servName]; ok {
+		return name
+	}
+	return grpcClientField(servName)
+}
+
+// grpcClientCall returns the expression calling method methName of the gRPC client of service servName.
+func (g *generator) grpcClientCall(servName, methName string) string {
+	return fmt.Sprintf("c.%s.%s(ctx, req, settings.GRPC...)", g.grpcClientField(servName), methName)
+}
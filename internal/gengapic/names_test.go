@@ -0,0 +1,208 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestPlanNames(t *testing.T) {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+	otherFile := &descriptor.FileDescriptorProto{
+		Package: proto.String("other.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("otherpackage"),
+		},
+	}
+
+	// ListThings and ListOtherThings page over messages both named Thing.
+	listResp := func(name, elem string) *descriptor.DescriptorProto {
+		return &descriptor.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptor.FieldDescriptorProto{
+				{Name: proto.String("next_page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+				{
+					Name:     proto.String("things"),
+					Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+					TypeName: proto.String(elem),
+					Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+				},
+			},
+		}
+	}
+	types := map[string]*descriptor.DescriptorProto{
+		".my.pkg.Thing": {Name: proto.String("Thing")},
+		".my.pkg.ListThingsRequest": {
+			Name: proto.String("ListThingsRequest"),
+			Field: []*descriptor.FieldDescriptorProto{
+				{Name: proto.String("page_size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT32), Label: optional},
+				{Name: proto.String("page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			},
+		},
+		".my.pkg.ListThingsResponse":      listResp("ListThingsResponse", ".my.pkg.Thing"),
+		".my.pkg.ListOtherThingsResponse": listResp("ListOtherThingsResponse", ".other.pkg.Thing"),
+		".my.pkg.Request":                 {Name: proto.String("Request")},
+		".my.pkg.Response":                {Name: proto.String("Response")},
+	}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	commonTypes(&g)
+	for name, typ := range types {
+		g.descInfo.Type[name] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+	otherThing := &descriptor.DescriptorProto{Name: proto.String("Thing")}
+	g.descInfo.Type[".other.pkg.Thing"] = otherThing
+	g.descInfo.ParentFile[otherThing] = otherFile
+
+	unary := func(name string) *descriptor.MethodDescriptorProto {
+		return &descriptor.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".my.pkg.Request"),
+			OutputType: proto.String(".my.pkg.Response"),
+		}
+	}
+	closeMeth := unary("Close")
+	fooOpMeth := unary("FooOperation")
+	pagesMeth := unary("ListThingsPages")
+	fooMeth := &descriptor.MethodDescriptorProto{
+		Name:       proto.String("Foo"),
+		InputType:  proto.String(".my.pkg.Request"),
+		OutputType: proto.String(lroType),
+		Options:    &descriptor.MethodOptions{},
+	}
+	if err := proto.SetExtension(fooMeth.Options, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+		Response: "Response",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	listMeth := &descriptor.MethodDescriptorProto{
+		Name:       proto.String("ListThings"),
+		InputType:  proto.String(".my.pkg.ListThingsRequest"),
+		OutputType: proto.String(".my.pkg.ListThingsResponse"),
+	}
+	listOtherMeth := &descriptor.MethodDescriptorProto{
+		Name:       proto.String("ListOtherThings"),
+		InputType:  proto.String(".my.pkg.ListThingsRequest"),
+		OutputType: proto.String(".my.pkg.ListOtherThingsResponse"),
+	}
+
+	serv := &descriptor.ServiceDescriptorProto{
+		Name:    proto.String("Foo"),
+		Method:  []*descriptor.MethodDescriptorProto{closeMeth, fooMeth, fooOpMeth, listMeth, pagesMeth, listOtherMeth},
+		Options: &descriptor.ServiceOptions{},
+	}
+	if err := proto.SetExtension(serv.Options, annotations.E_DefaultHost, proto.String("foo.googleapis.com")); err != nil {
+		t.Fatal(err)
+	}
+	g.descInfo.ParentFile[serv] = file
+
+	if _, err := g.planNames([]*descriptor.ServiceDescriptorProto{serv}, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tst := range []struct {
+		what, got, want string
+	}{
+		{"Close method", g.clientMethodName(closeMeth), "Close_"},
+		{"FooOperation method", g.clientMethodName(fooOpMeth), "FooOperation"},
+		{"Foo operation type", g.lroTypeName(fooMeth), "FooOperation_"},
+		{"ListThingsPages method", g.clientMethodName(pagesMeth), "ListThingsPages"},
+		{"ListThings pages method", g.pagesMethodName(listMeth), "ListThingsPages_"},
+		{"ListThings page type", g.pageTypeName(listMeth), "ListThingsPage"},
+		{"ListThings page iterator type", g.pageIterTypeName(listMeth), "ListThingsPageIterator"},
+		{"gRPC client field", g.grpcClientField(""), "fooClient"},
+	} {
+		if tst.got != tst.want {
+			t.Errorf("%s: got %q, want %q", tst.what, tst.got, tst.want)
+		}
+	}
+
+	for _, tst := range []struct {
+		field *descriptor.FieldDescriptorProto
+		want  string
+	}{
+		{types[".my.pkg.ListThingsResponse"].GetField()[1], "ThingIterator"},
+		{types[".my.pkg.ListOtherThingsResponse"].GetField()[1], "ThingIterator_"},
+	} {
+		iter, err := g.iterTypeOf(tst.field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if iter.iterTypeName != tst.want {
+			t.Errorf("iterator over %s: got %q, want %q", tst.field.GetTypeName(), iter.iterTypeName, tst.want)
+		}
+	}
+
+	if got, want := len(g.names.renames), 5; got != want {
+		t.Errorf("got %d renames, want %d: %q", got, want, g.names.renames)
+	}
+
+	// The client uses the planned names.
+	g.reset()
+	if err := g.gen(serv, "foo", map[string]*descriptor.FieldDescriptorProto{}); err != nil {
+		t.Fatal(err)
+	}
+	got := g.pt.String()
+	for _, want := range []string{
+		"fooClient mypackagepb.FooClient",
+		"func (c *Client) Close_(ctx context.Context",
+		"func (c *Client) FooOperation(ctx context.Context",
+		"func (c *Client) Foo(ctx context.Context, req *mypackagepb.Request, opts ...gax.CallOption) (*FooOperation_, error)",
+		"func (c *Client) FooOperation_(name string) *FooOperation_",
+		"func (c *Client) ListThingsPages_(ctx context.Context, req *mypackagepb.ListThingsRequest, opts ...gax.CallOption) *ListThingsPageIterator",
+		"func (c *Client) ListOtherThings(ctx context.Context, req *mypackagepb.ListThingsRequest, opts ...gax.CallOption) *ThingIterator_",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("client does not contain %q", want)
+		}
+	}
+}
+
+func TestPlanNamesError(t *testing.T) {
+	var g generator
+	servs := []*descriptor.ServiceDescriptorProto{
+		{Name: proto.String("Foo")},
+		{Name: proto.String("FooService")},
+	}
+	planned, err := g.planNames(servs, "foo")
+	if err == nil {
+		t.Errorf("planNames(%q, %q) = nil, want error: both clients are named Client", []string{"Foo", "FooService"}, "foo")
+	}
+	// The first service is still generated.
+	if len(planned) != 1 || planned[0] != servs[0] {
+		t.Errorf("planNames(%q, %q) planned %v, want only Foo", []string{"Foo", "FooService"}, "foo", planned)
+	}
+}
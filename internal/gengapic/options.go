@@ -0,0 +1,171 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+)
+
+type transport int
+
+const (
+	grpcTransport transport = iota
+	restTransport
+)
+
+var transportNames = map[string]transport{
+	"grpc": grpcTransport,
+	"rest": restTransport,
+}
+
+// options contains the settings passed to the plugin in CodeGeneratorRequest.Parameter.
+type options struct {
+	// Import path and name of the generated package.
+	pkgPath, pkgName string
+
+	// Transports to generate clients for.
+	// The zero value means gRPC only.
+	transports []transport
+
+	// Path to the gRPC service config file, or empty if there is none.
+	grpcConfPath string
+
+	// Path to the GAPIC config file, or empty if there is none.
+	gapicConfPath string
+
+	// Whether to generate an interface for each client.
+	clientInterface bool
+
+	// Maximum number of errors reported. Zero means defaultMaxErrors.
+	maxErrors int
+}
+
+// defaultMaxErrors is the number of errors reported when the max-errors option is not set.
+const defaultMaxErrors = 20
+
+// hasTransport reports whether clients should be generated for transport t.
+func (o *options) hasTransport(t transport) bool {
+	if len(o.transports) == 0 {
+		return t == grpcTransport
+	}
+	for _, ot := range o.transports {
+		if ot == t {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOptions parses the plugin parameter.
+//
+// The parameter is a comma-separated list of key=value pairs, for example
+//
+//	package-path=cloud.google.com/go/pubsub/apiv1,package-name=pubsub,transport=grpc
+//
+// For backward compatibility, an element of the form "client/import/path;packageName"
+// sets both the package path and the package name.
+func parseOptions(parameter *string) (*options, error) {
+	if parameter == nil {
+		return nil, errors.User(nil, "need parameter in format: package-path=client/import/path,package-name=packageName")
+	}
+
+	var opts options
+	for _, s := range strings.Split(*parameter, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		e := strings.IndexByte(s, '=')
+		if e < 0 {
+			p := strings.IndexByte(s, ';')
+			if p < 0 {
+				return nil, errors.User(nil, "invalid option %q, expected key=value or client/import/path;packageName", s)
+			}
+			opts.pkgPath = s[:p]
+			opts.pkgName = s[p+1:]
+			continue
+		}
+
+		key, val := strings.TrimSpace(s[:e]), strings.TrimSpace(s[e+1:])
+		if val == "" {
+			return nil, errors.User(nil, "option %q needs a value", key)
+		}
+
+		switch key {
+		case "package-path":
+			opts.pkgPath = val
+		case "package-name":
+			opts.pkgName = val
+		case "transport":
+			opts.transports = opts.transports[:0]
+			for _, name := range strings.Split(val, "+") {
+				t, ok := transportNames[name]
+				if !ok {
+					return nil, errors.User(nil, "invalid transport %q in option %q", name, s)
+				}
+				opts.transports = append(opts.transports, t)
+			}
+		case "grpc-service-config":
+			opts.grpcConfPath = val
+		case "gapic-config":
+			opts.gapicConfPath = val
+		case "client-interface":
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, errors.User(nil, "invalid value %q in option %q, expected a boolean", val, s)
+			}
+			opts.clientInterface = b
+		case "max-errors":
+			n, err := strconv.Atoi(val)
+			if err != nil || n <= 0 {
+				return nil, errors.User(nil, "invalid value %q in option %q, expected a positive integer", val, s)
+			}
+			opts.maxErrors = n
+		default:
+			return nil, errors.User(nil, "unknown option %q", key)
+		}
+	}
+
+	if opts.pkgPath == "" {
+		return nil, errors.User(nil, "need package-path option, or parameter in format: client/import/path;packageName")
+	}
+	if opts.pkgName == "" {
+		opts.pkgName = path.Base(opts.pkgPath)
+	}
+	if !isIdent(opts.pkgName) {
+		return nil, errors.User(nil, "invalid package name %q", opts.pkgName)
+	}
+	return &opts, nil
+}
+
+// isIdent reports whether s can be used as a package name: an identifier that is not a keyword.
+func isIdent(s string) bool {
+	if s == "" || token.Lookup(s).IsKeyword() {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+)
+
+func TestParseOptions(t *testing.T) {
+	for _, tst := range []struct {
+		param  *string
+		want   *options
+		expErr bool
+	}{
+		{
+			param: proto.String("path/to/awesome;awesome"),
+			want:  &options{pkgPath: "path/to/awesome", pkgName: "awesome"},
+		},
+		{
+			param: proto.String("package-path=path/to/awesome,package-name=awesome"),
+			want:  &options{pkgPath: "path/to/awesome", pkgName: "awesome"},
+		},
+		{
+			param: proto.String("package-path=path/to/awesome"),
+			want:  &options{pkgPath: "path/to/awesome", pkgName: "awesome"},
+		},
+		{
+			param: proto.String("path/to/awesome;awesome, transport=grpc"),
+			want: &options{
+				pkgPath:    "path/to/awesome",
+				pkgName:    "awesome",
+				transports: []transport{grpcTransport},
+			},
+		},
+		{
+			param: proto.String("package-path=path/to/awesome,transport=grpc+rest"),
+			want: &options{
+				pkgPath:    "path/to/awesome",
+				pkgName:    "awesome",
+				transports: []transport{grpcTransport, restTransport},
+			},
+		},
+		{
+			param: proto.String("package-path=path/to/awesome,grpc-service-config=path/to/conf.json"),
+			want: &options{
+				pkgPath:      "path/to/awesome",
+				pkgName:      "awesome",
+				grpcConfPath: "path/to/conf.json",
+			},
+		},
+		{
+			param: proto.String("package-path=path/to/awesome,gapic-config=path/to/gapic.yaml"),
+			want: &options{
+				pkgPath:       "path/to/awesome",
+				pkgName:       "awesome",
+				gapicConfPath: "path/to/gapic.yaml",
+			},
+		},
+		{
+			param: proto.String("package-path=path/to/awesome,client-interface=true"),
+			want: &options{
+				pkgPath:         "path/to/awesome",
+				pkgName:         "awesome",
+				clientInterface: true,
+			},
+		},
+		{
+			param: proto.String("package-path=path/to/awesome,max-errors=5"),
+			want: &options{
+				pkgPath:   "path/to/awesome",
+				pkgName:   "awesome",
+				maxErrors: 5,
+			},
+		},
+		{
+			param:  nil,
+			expErr: true,
+		},
+		{
+			param:  proto.String("path/to/awesome"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("package-name=awesome"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("package-path=path/to/awesome,package-name=not-a-name"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("package-path=path/to/awesome,package-name="),
+			expErr: true,
+		},
+		{
+			param:  proto.String("package-path=path/to/awesome,package-name=func"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("package-path=path/to/go"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("path/to/awesome;awesome,transport=carrier-pigeon"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("package-path=path/to/awesome,client-interface=maybe"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("package-path=path/to/awesome,max-errors=0"),
+			expErr: true,
+		},
+		{
+			param:  proto.String("path/to/awesome;awesome,bogus=true"),
+			expErr: true,
+		},
+	} {
+		var param string
+		if tst.param != nil {
+			param = *tst.param
+		}
+		got, err := parseOptions(tst.param)
+		if tst.expErr {
+			if err == nil {
+				t.Errorf("parseOptions(%q) = %+v, expected error", param, got)
+			} else if !errors.IsUser(err) {
+				t.Errorf("parseOptions(%q) = %v, want a user error", param, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseOptions(%q) errors: %v", param, err)
+			continue
+		}
+		if diff := cmp.Diff(got, tst.want, cmp.AllowUnexported(options{})); diff != "" {
+			t.Errorf("parseOptions(%q): (-got,+want)\n%s", param, diff)
+		}
+	}
+}
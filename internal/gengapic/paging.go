@@ -16,10 +16,11 @@ package gengapic
 
 import (
 	"fmt"
-	"log"
+	"strings"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
 )
 
@@ -27,33 +28,61 @@ import (
 type iterType struct {
 	iterTypeName, elemTypeName string
 
-	// If the elem type is a message, elemImports contains pbinfo.ImportSpec for the type.
+	// If the elem type is a message or an enum, elemImports contains pbinfo.ImportSpec for the type.
 	// Otherwise, len(elemImports)==0.
 	elemImports []pbinfo.ImportSpec
+
+	// If the field iterated over is a map, elemTypeName is a pair type generated along with the iterator,
+	// and keyTypeName and valueTypeName are the Go types of its Key and Value fields.
+	// Otherwise, both are empty.
+	keyTypeName, valueTypeName string
 }
 
 // iterTypeOf deduces iterType from a field to be iterated over.
 // elemField should be the "resource" of a paginating RPC.
 func (g *generator) iterTypeOf(elemField *descriptor.FieldDescriptorProto) (iterType, error) {
+	pt, err := g.defaultIterTypeOf(elemField)
+	if err != nil {
+		return iterType{}, err
+	}
+	if in, ok := g.names.iters[g.iterKey(elemField)]; ok {
+		pt.iterTypeName = in.iter
+		if in.pair != "" {
+			pt.elemTypeName = in.pair
+		}
+	}
+	return pt, nil
+}
+
+// defaultIterTypeOf deduces iterType from a field to be iterated over,
+// naming the types after the element type regardless of the names planned by planNames.
+func (g *generator) defaultIterTypeOf(elemField *descriptor.FieldDescriptorProto) (iterType, error) {
 	var pt iterType
 
-	switch t := *elemField.Type; {
-	case t == descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+	switch t := elemField.GetType(); {
+	case t == descriptor.FieldDescriptorProto_TYPE_MESSAGE || t == descriptor.FieldDescriptorProto_TYPE_ENUM:
 		eType := g.descInfo.Type[elemField.GetTypeName()]
+		if eType == nil {
+			return iterType{}, errors.E(nil, "cannot find type %q, malformed descriptor?", elemField.GetTypeName())
+		}
+
+		if entry := mapEntry(eType); entry != nil {
+			return g.pairIterTypeOf(entry)
+		}
 
-		imp, err := g.descInfo.ImportSpec(eType)
+		name, imp, err := g.goTypeName(eType)
 		if err != nil {
 			return iterType{}, err
 		}
 
-		pt.elemTypeName = fmt.Sprintf("*%s.%s", imp.Name, eType.GetName())
+		pt.elemTypeName = name
+		if t == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			pt.elemTypeName = "*" + name
+		}
 		pt.iterTypeName = eType.GetName() + "Iterator"
 
 		pt.elemImports = []pbinfo.ImportSpec{imp}
 
-	case t == descriptor.FieldDescriptorProto_TYPE_ENUM:
-		log.Panic("iterating enum not supported yet")
-
 	case t == descriptor.FieldDescriptorProto_TYPE_BYTES:
 		pt.elemTypeName = "[]byte"
 		pt.iterTypeName = "BytesIterator"
@@ -61,7 +90,7 @@ func (g *generator) iterTypeOf(elemField *descriptor.FieldDescriptorProto) (iter
 	default:
 		pType := pbinfo.GoTypeForPrim[t]
 		if pType == "" {
-			log.Panicf("unrecognized type: %v", t)
+			return iterType{}, errors.At(g.pos(elemField), errors.User(nil, "cannot iterate over type %v", t), "field %s", elemField.GetName())
 		}
 		pt.elemTypeName = pType
 		pt.iterTypeName = upperFirst(pt.elemTypeName) + "Iterator"
@@ -69,17 +98,72 @@ func (g *generator) iterTypeOf(elemField *descriptor.FieldDescriptorProto) (iter
 	return pt, nil
 }
 
-// TODO(pongad): this will probably need to read from annotations later.
+// mapEntry returns the map entry message t, or nil if t is not a map entry.
+func mapEntry(t pbinfo.ProtoType) *descriptor.DescriptorProto {
+	if msg, ok := t.(*descriptor.DescriptorProto); ok && msg.GetOptions().GetMapEntry() {
+		return msg
+	}
+	return nil
+}
+
+// pairIterTypeOf deduces the iterType of a map field with the given map entry type.
+// The iterator returns pairs named after the key and value types,
+// so map<string, Foo> gives StringFooPairIterator over StringFooPair.
+func (g *generator) pairIterTypeOf(entry *descriptor.DescriptorProto) (iterType, error) {
+	var pt iterType
+
+	keyField, valueField := findField(entry, "key"), findField(entry, "value")
+	if keyField == nil || valueField == nil {
+		return iterType{}, errors.E(nil, "map entry %s needs key and value fields, malformed descriptor?", entry.GetName())
+	}
+
+	var err error
+	var imps []pbinfo.ImportSpec
+	if pt.keyTypeName, imps, err = g.fieldGoType(keyField); err != nil {
+		return iterType{}, err
+	}
+	pt.elemImports = append(pt.elemImports, imps...)
+	if pt.valueTypeName, imps, err = g.fieldGoType(valueField); err != nil {
+		return iterType{}, err
+	}
+	pt.elemImports = append(pt.elemImports, imps...)
+
+	pt.elemTypeName = typeIdent(pt.keyTypeName) + typeIdent(pt.valueTypeName) + "Pair"
+	pt.iterTypeName = pt.elemTypeName + "Iterator"
+	return pt, nil
+}
+
+// typeIdent returns an identifier describing Go type typ, for use in names of generated types.
+// For example, "*foopb.Foo_Bar" gives "FooBar" and "[]byte" gives "Bytes".
+func typeIdent(typ string) string {
+	if typ == "[]byte" {
+		return "Bytes"
+	}
+	typ = strings.TrimPrefix(typ, "*")
+	typ = typ[strings.LastIndexByte(typ, '.')+1:]
+	return upperFirst(strings.Replace(typ, "_", "", -1))
+}
+
+// pagingInfo describes the fields used to paginate a method.
+type pagingInfo struct {
+	// Page size field of the request, or nil if the page size cannot be set.
+	sizeField *descriptor.FieldDescriptorProto
+
+	// Page token fields of the request and the response.
+	tokenField, nextTokenField *descriptor.FieldDescriptorProto
 
-// pagingField reports the "resource field" to be iterated over by paginating method m.
-// If the method is not a paging method, pagingField returns (nil, nil).
-// If the method looks like a paging method, but the field cannot be determined, pagingField errors.
-func (g *generator) pagingField(m *descriptor.MethodDescriptorProto) (*descriptor.FieldDescriptorProto, error) {
-	var (
-		hasSize, hasToken, hasNextToken bool
-		elemFields                      []*descriptor.FieldDescriptorProto
-	)
+	// The repeated field of the response iterated over.
+	elemField *descriptor.FieldDescriptorProto
+}
 
+// pagingInfoOf reports how method m of serv is paginated.
+// If the method is not a paging method, pagingInfoOf returns (nil, nil).
+//
+// The fields are read from the GAPIC config if the method is configured.
+// Otherwise, a method is paginated if its request has int32 page_size and string page_token fields,
+// and its response has a string next_page_token field; the response must then have
+// exactly one repeated field to iterate over, or pagingInfoOf errors.
+func (g *generator) pagingInfoOf(serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) (*pagingInfo, error) {
 	inType := g.descInfo.Type[m.GetInputType()]
 	if inType == nil {
 		return nil, errors.E(nil, "cannot find message type %q, malformed descriptor?", m.GetInputType())
@@ -98,23 +182,32 @@ func (g *generator) pagingField(m *descriptor.MethodDescriptorProto) (*descripto
 		return nil, errors.E(nil, "expected %q to be message type, found %T", m.GetOutputType(), outType)
 	}
 
+	if pc := g.gapicConf.pagingConfig(g.fullyQualifiedName(serv), m.GetName()); pc != nil {
+		if pc.disabled {
+			return nil, nil
+		}
+		return g.configuredPaging(inMsg, outMsg, pc)
+	}
+
+	var pi pagingInfo
 	for _, f := range inMsg.Field {
 		if f.GetName() == "page_size" && f.GetType() == descriptor.FieldDescriptorProto_TYPE_INT32 {
-			hasSize = true
+			pi.sizeField = f
 		}
 		if f.GetName() == "page_token" && f.GetType() == descriptor.FieldDescriptorProto_TYPE_STRING {
-			hasToken = true
+			pi.tokenField = f
 		}
 	}
+	var elemFields []*descriptor.FieldDescriptorProto
 	for _, f := range outMsg.Field {
 		if f.GetName() == "next_page_token" && f.GetType() == descriptor.FieldDescriptorProto_TYPE_STRING {
-			hasNextToken = true
+			pi.nextTokenField = f
 		}
 		if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
 			elemFields = append(elemFields, f)
 		}
 	}
-	if !hasSize || !hasToken || !hasNextToken {
+	if pi.sizeField == nil || pi.tokenField == nil || pi.nextTokenField == nil {
 		return nil, nil
 	}
 	if len(elemFields) == 0 {
@@ -123,48 +216,123 @@ func (g *generator) pagingField(m *descriptor.MethodDescriptorProto) (*descripto
 	if len(elemFields) > 1 {
 		return nil, fmt.Errorf("%s looks like paging method, but too many repeated fields in %s", *m.Name, outType.GetName())
 	}
-	return elemFields[0], nil
+	pi.elemField = elemFields[0]
+	return &pi, nil
 }
 
-func (g *generator) pagingCall(servName string, m *descriptor.MethodDescriptorProto, elemField *descriptor.FieldDescriptorProto, pt iterType) error {
+// configuredPaging looks up the fields named by pc in the request and response messages.
+func (g *generator) configuredPaging(inMsg, outMsg *descriptor.DescriptorProto, pc *pagingConfig) (*pagingInfo, error) {
+	var pi pagingInfo
+
+	if pc.pageSizeField != "" {
+		f := findField(inMsg, pc.pageSizeField)
+		if f == nil {
+			return nil, errors.User(nil, "cannot find page size field %q in %s", pc.pageSizeField, inMsg.GetName())
+		}
+		if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED || pageSizeTypes[f.GetType()] == "" {
+			return nil, errors.At(g.pos(f), errors.User(nil, "must be a 32 or 64-bit integer"), "page size field %s of %s", pc.pageSizeField, inMsg.GetName())
+		}
+		pi.sizeField = f
+	}
+
+	tokenField := func(msg *descriptor.DescriptorProto, name string) (*descriptor.FieldDescriptorProto, error) {
+		f := findField(msg, name)
+		if f == nil {
+			return nil, errors.User(nil, "cannot find page token field %q in %s", name, msg.GetName())
+		}
+		if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED || f.GetType() != descriptor.FieldDescriptorProto_TYPE_STRING {
+			return nil, errors.At(g.pos(f), errors.User(nil, "must be a string"), "page token field %s of %s", name, msg.GetName())
+		}
+		return f, nil
+	}
+	var err error
+	if pi.tokenField, err = tokenField(inMsg, pc.tokenField); err != nil {
+		return nil, err
+	}
+	if pi.nextTokenField, err = tokenField(outMsg, pc.nextTokenField); err != nil {
+		return nil, err
+	}
+
+	f := findField(outMsg, pc.resourcesField)
+	if f == nil {
+		return nil, errors.User(nil, "cannot find resources field %q in %s", pc.resourcesField, outMsg.GetName())
+	}
+	if f.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return nil, errors.At(g.pos(f), errors.User(nil, "must be repeated"), "resources field %s of %s", pc.resourcesField, outMsg.GetName())
+	}
+	pi.elemField = f
+	return &pi, nil
+}
+
+// pageSizeTypes maps the types allowed for page size fields to their Go types.
+var pageSizeTypes = map[descriptor.FieldDescriptorProto_Type]string{
+	descriptor.FieldDescriptorProto_TYPE_INT32:    "int32",
+	descriptor.FieldDescriptorProto_TYPE_SINT32:   "int32",
+	descriptor.FieldDescriptorProto_TYPE_SFIXED32: "int32",
+	descriptor.FieldDescriptorProto_TYPE_INT64:    "int64",
+	descriptor.FieldDescriptorProto_TYPE_SINT64:   "int64",
+	descriptor.FieldDescriptorProto_TYPE_SFIXED64: "int64",
+	descriptor.FieldDescriptorProto_TYPE_UINT32:   "uint32",
+	descriptor.FieldDescriptorProto_TYPE_FIXED32:  "uint32",
+	descriptor.FieldDescriptorProto_TYPE_UINT64:   "uint64",
+	descriptor.FieldDescriptorProto_TYPE_FIXED64:  "uint64",
+}
+
+func (g *generator) pagingCall(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, pi *pagingInfo, pt iterType) error {
 	inType := g.descInfo.Type[*m.InputType]
 	outType := g.descInfo.Type[*m.OutputType]
 
-	inSpec, err := g.descInfo.ImportSpec(inType)
+	inSpec, err := g.importSpec(inType)
 	if err != nil {
 		return err
 	}
-	outSpec, err := g.descInfo.ImportSpec(outType)
+	outSpec, err := g.importSpec(outType)
 	if err != nil {
 		return err
 	}
 
 	p := g.printf
-	p("func (c *%sClient) %s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) *%s {",
-		servName, *m.Name, inSpec.Name, inType.GetName(), pt.iterTypeName)
+	// The iterator is shared with other methods, so the type of its Response is documented here.
+	if g.comments[m] != "" {
+		p("//")
+	}
+	p("// The Response of the returned iterator is the *%s.%s of the page last fetched.", outSpec.Name, outType.GetName())
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) *%s",
+		g.clientMethodName(m), inSpec.Name, inType.GetName(), pt.iterTypeName))
 
-	g.insertMetadata()
+	if err := g.insertMetadata(m); err != nil {
+		return err
+	}
 	g.appendCallOpts(m)
 
 	p("it := &%s{}", pt.iterTypeName)
 	p("req = proto.Clone(req).(*%s.%s)", inSpec.Name, inType.GetName())
 	p("it.InternalFetch = func(pageSize int, pageToken string) ([]%s, string, error) {", pt.elemTypeName)
+	// Each page is fetched with the timeout.
+	g.callTimeout(serv, m, true)
 	p("  var resp *%s.%s", outSpec.Name, outType.GetName())
-	p("  req.PageToken = pageToken")
-	p("  if pageSize > math.MaxInt32 {")
-	p("    req.PageSize = math.MaxInt32")
-	p("  } else {")
-	p("    req.PageSize = int32(pageSize)")
-	p("  }")
+	p("  req.%s = pageToken", naming.CamelCase(pi.tokenField.GetName()))
+	if pi.sizeField != nil {
+		g.setPageSize(pi.sizeField)
+	}
 	p("  err := gax.Invoke(ctx, func(ctx context.Context, settings gax.CallSettings) error {")
 	p("    var err error")
-	p("    resp, err = %s", grpcClientCall(servName, *m.Name))
+	p("    resp, err = %s", g.grpcClientCall(servName, *m.Name))
 	p("    return err")
 	p("  }, opts...)")
 	p("  if err != nil {")
 	p("    return nil, \"\", err")
 	p("  }")
-	p("  return resp.%s, resp.NextPageToken, nil", snakeToCamel(*elemField.Name))
+	p("  it.Response = resp")
+	elems := "resp." + naming.CamelCase(pi.elemField.GetName())
+	if pt.keyTypeName != "" {
+		p("  elems := make([]%s, 0, len(%s))", pt.elemTypeName, elems)
+		p("  for k, v := range %s {", elems)
+		p("    elems = append(elems, %s{Key: k, Value: v})", pt.elemTypeName)
+		p("  }")
+		elems = "elems"
+	}
+	p("  return %s, resp.%s, nil", elems, naming.CamelCase(pi.nextTokenField.GetName()))
 	p("}")
 
 	p("fetch := func(pageSize int, pageToken string) (string, error) {")
@@ -177,13 +345,14 @@ func (g *generator) pagingCall(servName string, m *descriptor.MethodDescriptorPr
 	p("}")
 
 	p("it.pageInfo, it.nextFunc = iterator.NewPageInfo(fetch, it.bufLen, it.takeBuf)")
-	p("it.pageInfo.MaxSize = int(req.PageSize)")
+	if pi.sizeField != nil {
+		p("it.pageInfo.MaxSize = int(req.%s)", naming.CamelCase(pi.sizeField.GetName()))
+	}
 	p("return it")
 
 	p("}")
 	p("")
 
-	g.imports[pbinfo.ImportSpec{Path: "math"}] = true
 	g.imports[pbinfo.ImportSpec{Path: "github.com/golang/protobuf/proto"}] = true
 	g.imports[pbinfo.ImportSpec{Path: "google.golang.org/api/iterator"}] = true
 	g.imports[inSpec] = true
@@ -194,15 +363,152 @@ func (g *generator) pagingCall(servName string, m *descriptor.MethodDescriptorPr
 	return nil
 }
 
+// pageType describes the types generated for the page-by-page iteration of a paging method.
+type pageType struct {
+	method *descriptor.MethodDescriptorProto
+
+	// The iterator over the elements of the pages.
+	iter iterType
+}
+
+// pagesCall generates the method iterating over the pages of results of paging method m.
+// It must be generated along with the method generated by pagingCall, whose InternalFetch it uses.
+func (g *generator) pagesCall(servName string, m *descriptor.MethodDescriptorProto, pi *pagingInfo) error {
+	inType := g.descInfo.Type[m.GetInputType()]
+	inSpec, err := g.importSpec(inType)
+	if err != nil {
+		return err
+	}
+	g.imports[inSpec] = true
+
+	p := g.printf
+	iterName := g.pageIterTypeName(m)
+	pagesName := g.pagesMethodName(m)
+
+	p("// %s is like %s, but iterates over pages of results rather than over single results.", pagesName, g.clientMethodName(m))
+	p("// Each page gives access to the full response message of the page.")
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) *%s",
+		pagesName, inSpec.Name, inType.GetName(), iterName))
+	p("  return &%s{", iterName)
+	p("    it: c.%s(ctx, req, opts...),", g.clientMethodName(m))
+	if pi.sizeField != nil {
+		p("    pageSize: int(req.%s),", naming.CamelCase(pi.sizeField.GetName()))
+	}
+	p("    pageToken: req.%s,", naming.CamelCase(pi.tokenField.GetName()))
+	p("  }")
+	p("}")
+	p("")
+	return nil
+}
+
+// pageTypes generates the types for the page-by-page iteration of paging method pt.method.
+func (g *generator) pageTypes(pt pageType) error {
+	outType := g.descInfo.Type[pt.method.GetOutputType()]
+	outSpec, err := g.importSpec(outType)
+	if err != nil {
+		return err
+	}
+	g.imports[outSpec] = true
+
+	p := g.printf
+	pageName := g.pageTypeName(pt.method)
+	iterName := g.pageIterTypeName(pt.method)
+
+	p("// %s is a page of results of %s.", pageName, g.clientMethodName(pt.method))
+	p("type %s struct {", pageName)
+	p("  // Response is the response message of the page.")
+	p("  Response *%s.%s", outSpec.Name, outType.GetName())
+	p("")
+	p("  // Items are the results in the page.")
+	p("  Items []%s", pt.iter.elemTypeName)
+	p("}")
+	p("")
+
+	p("// %s manages a stream of pages of results of %s.", iterName, g.clientMethodName(pt.method))
+	p("type %s struct {", iterName)
+	p("  it        *%s", pt.iter.iterTypeName)
+	p("  pageSize  int")
+	p("  pageToken string")
+	p("  done      bool")
+	p("}")
+	p("")
+
+	p("// Next returns the next page. Its second return value is iterator.Done if there are no more")
+	p("// pages. Once Next returns Done, all subsequent calls will return Done.")
+	p("func (it *%s) Next() (*%s, error) {", iterName, pageName)
+	p("  if it.done {")
+	p("    return nil, iterator.Done")
+	p("  }")
+	p("  items, nextPageToken, err := it.it.InternalFetch(it.pageSize, it.pageToken)")
+	p("  if err != nil {")
+	p("    return nil, err")
+	p("  }")
+	p("  it.pageToken = nextPageToken")
+	p("  it.done = nextPageToken == \"\"")
+	p("  return &%s{", pageName)
+	p("    Response: it.it.Response.(*%s.%s),", outSpec.Name, outType.GetName())
+	p("    Items:    items,")
+	p("  }, nil")
+	p("}")
+	p("")
+
+	g.imports[pbinfo.ImportSpec{Path: "google.golang.org/api/iterator"}] = true
+	return nil
+}
+
+// setPageSize prints the code setting page size field f of req to pageSize,
+// clamped to the range of the field.
+func (g *generator) setPageSize(f *descriptor.FieldDescriptorProto) {
+	p := g.printf
+
+	name := naming.CamelCase(f.GetName())
+	switch typ := pageSizeTypes[f.GetType()]; typ {
+	case "int32":
+		p("  if pageSize > math.MaxInt32 {")
+		p("    req.%s = math.MaxInt32", name)
+		p("  } else {")
+		p("    req.%s = int32(pageSize)", name)
+		p("  }")
+		g.imports[pbinfo.ImportSpec{Path: "math"}] = true
+	case "uint32":
+		p("  if uint64(pageSize) > math.MaxUint32 {")
+		p("    req.%s = math.MaxUint32", name)
+		p("  } else {")
+		p("    req.%s = uint32(pageSize)", name)
+		p("  }")
+		g.imports[pbinfo.ImportSpec{Path: "math"}] = true
+	default:
+		p("  req.%s = %s(pageSize)", name, typ)
+	}
+}
+
 func (g *generator) pagingIter(pt iterType) {
 	p := g.printf
 
+	if pt.keyTypeName != "" {
+		p("// %s is an entry of a map returned by %s.", pt.elemTypeName, pt.iterTypeName)
+		p("// The entries of each page are returned in unspecified order.")
+		p("type %s struct {", pt.elemTypeName)
+		p("  Key   %s", pt.keyTypeName)
+		p("  Value %s", pt.valueTypeName)
+		p("}")
+		p("")
+	}
+
 	p("// %s manages a stream of %s.", pt.iterTypeName, pt.elemTypeName)
+	p("// It is shared by the methods iterating over %s; the Pages variant of each method", pt.elemTypeName)
+	p("// returns the typed response messages along with the results of each page.")
 	p("type %s struct {", pt.iterTypeName)
 	p("  items    []%s", pt.elemTypeName)
 	p("  pageInfo *iterator.PageInfo")
 	p("  nextFunc func() error")
 	p("")
+	p("  // Response is the raw response for the current page.")
+	p("  // It must be cast to the response type of the method that created the iterator,")
+	p("  // given in the documentation of the method.")
+	p("  // Calling Next() or InternalFetch() updates this value.")
+	p("  Response interface{}")
+	p("")
 	p("  // InternalFetch is for use by the Google Cloud Libraries only.")
 	p("  // It is not part of the stable interface of this package.")
 	p("  //")
@@ -243,4 +549,9 @@ func (g *generator) pagingIter(pt iterType) {
 	p("  return b")
 	p("}")
 	p("")
+
+	g.imports[pbinfo.ImportSpec{Path: "google.golang.org/api/iterator"}] = true
+	for _, spec := range pt.elemImports {
+		g.imports[spec] = true
+	}
 }
@@ -15,6 +15,8 @@
 package gengapic
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
@@ -23,7 +25,7 @@ import (
 	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
 )
 
-func TestPagingField(t *testing.T) {
+func TestPagingInfoOf(t *testing.T) {
 	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
 		return &t
 	}
@@ -37,7 +39,47 @@ func TestPagingField(t *testing.T) {
 		Label: labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
 	}
 
+	resField2 := &descriptor.FieldDescriptorProto{
+		Name:  proto.String("other_resource"),
+		Type:  typep(descriptor.FieldDescriptorProto_TYPE_STRING),
+		Label: labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+	}
+
 	g := &generator{}
+	conf, err := parseGAPICConfig(strings.NewReader(`
+interfaces:
+- name: Serv
+  methods:
+  - name: MaxResults
+    page_streaming:
+      request:
+        page_size_field: max_results
+        token_field: page_token
+      response:
+        token_field: next_page_token
+        resources_field: other_resource
+  - name: NoSize
+    page_streaming:
+      request:
+        token_field: page_token
+      response:
+        token_field: next_page_token
+        resources_field: resource
+  - name: BadSize
+    page_streaming:
+      request:
+        page_size_field: page_token
+        token_field: page_token
+      response:
+        token_field: next_page_token
+        resources_field: resource
+  - name: Disabled
+    disable_paging: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.gapicConf = conf
 	g.descInfo.Type = map[string]pbinfo.ProtoType{
 		"Foo": &descriptor.DescriptorProto{
 			Name: proto.String("Foo"),
@@ -68,6 +110,33 @@ func TestPagingField(t *testing.T) {
 				resField,
 			},
 		},
+		"MaxResultsIn": &descriptor.DescriptorProto{
+			Name: proto.String("MaxResultsIn"),
+			Field: []*descriptor.FieldDescriptorProto{
+				{
+					Name:  proto.String("max_results"),
+					Type:  typep(descriptor.FieldDescriptorProto_TYPE_UINT32),
+					Label: labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL),
+				},
+				{
+					Name:  proto.String("page_token"),
+					Type:  typep(descriptor.FieldDescriptorProto_TYPE_STRING),
+					Label: labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL),
+				},
+			},
+		},
+		"MultiPageOut": &descriptor.DescriptorProto{
+			Name: proto.String("MultiPageOut"),
+			Field: []*descriptor.FieldDescriptorProto{
+				{
+					Name:  proto.String("next_page_token"),
+					Type:  typep(descriptor.FieldDescriptorProto_TYPE_STRING),
+					Label: labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL),
+				},
+				resField,
+				resField2,
+			},
+		},
 		"BadPageOut1": &descriptor.DescriptorProto{
 			Name: proto.String("BadPageOut1"),
 			Field: []*descriptor.FieldDescriptorProto{
@@ -94,10 +163,12 @@ func TestPagingField(t *testing.T) {
 		},
 	}
 
+	serv := &descriptor.ServiceDescriptorProto{Name: proto.String("Serv")}
+
 	for _, tst := range []struct {
-		in, out string
-		field   *descriptor.FieldDescriptorProto
-		err     bool
+		meth, in, out string
+		field         *descriptor.FieldDescriptorProto
+		err           bool
 	}{
 		{
 			in:  "Foo",
@@ -126,19 +197,56 @@ func TestPagingField(t *testing.T) {
 			out: "BadPageOut2",
 			err: true,
 		},
+		{
+			meth:  "MaxResults",
+			in:    "MaxResultsIn",
+			out:   "MultiPageOut",
+			field: resField2,
+		},
+		{
+			meth:  "NoSize",
+			in:    "MaxResultsIn",
+			out:   "PageOut",
+			field: resField,
+		},
+		{
+			meth: "MaxResults",
+			in:   "PageIn",
+			out:  "MultiPageOut",
+			err:  true,
+		},
+		{
+			meth: "BadSize",
+			in:   "PageIn",
+			out:  "PageOut",
+			err:  true,
+		},
+		{
+			meth: "Disabled",
+			in:   "PageIn",
+			out:  "PageOut",
+		},
 	} {
+		name := tst.meth
+		if name == "" {
+			name = "TestPagingInfoOf"
+		}
 		meth := &descriptor.MethodDescriptorProto{
-			Name:       proto.String("TestPagingField"),
+			Name:       proto.String(name),
 			InputType:  proto.String(tst.in),
 			OutputType: proto.String(tst.out),
 		}
-		f, err := g.pagingField(meth)
+		pi, err := g.pagingInfoOf(serv, meth)
+		var f *descriptor.FieldDescriptorProto
+		if pi != nil {
+			f = pi.elemField
+		}
 		if tst.err && err == nil {
-			t.Errorf("pagingField(%v)=%v, expected error", meth, f)
+			t.Errorf("pagingInfoOf(%v)=%v, expected error", meth, f)
 		} else if !tst.err && err != nil {
-			t.Errorf("pagingField(%v) errors %q, expected %v", meth, err, tst.field)
+			t.Errorf("pagingInfoOf(%v) errors %q, expected %v", meth, err, tst.field)
 		} else if f != tst.field {
-			t.Errorf("pagingField(%v)=%v, want %v", meth, f, tst.field)
+			t.Errorf("pagingInfoOf(%v)=%v, want %v", meth, f, tst.field)
 		}
 	}
 }
@@ -150,17 +258,35 @@ func TestIterTypeOf(t *testing.T) {
 	msgType := &descriptor.DescriptorProto{
 		Name: proto.String("Foo"),
 	}
+	enumType := &descriptor.EnumDescriptorProto{
+		Name: proto.String("State"),
+	}
+	entryType := &descriptor.DescriptorProto{
+		Name: proto.String("ThingsEntry"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("key"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT64)},
+			{Name: proto.String("value"), Type: typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE), TypeName: proto.String("Foo")},
+		},
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	fooFile := &descriptor.FileDescriptorProto{
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("path/to/foo;foo"),
+		},
+	}
 	g := &generator{
 		descInfo: pbinfo.Info{
 			Type: map[string]pbinfo.ProtoType{
 				msgType.GetName(): msgType,
+				"Foo.State":       enumType,
+				"Foo.ThingsEntry": entryType,
 			},
 			ParentFile: map[proto.Message]*descriptor.FileDescriptorProto{
-				msgType: &descriptor.FileDescriptorProto{
-					Options: &descriptor.FileOptions{
-						GoPackage: proto.String("path/to/foo;foo"),
-					},
-				},
+				msgType: fooFile,
+			},
+			ParentElement: map[pbinfo.ProtoType]pbinfo.ProtoType{
+				enumType:  msgType,
+				entryType: msgType,
 			},
 		},
 	}
@@ -198,6 +324,30 @@ func TestIterTypeOf(t *testing.T) {
 				elemImports:  []pbinfo.ImportSpec{{Name: "foopb", Path: "path/to/foo"}},
 			},
 		},
+		{
+			field: &descriptor.FieldDescriptorProto{
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_ENUM),
+				TypeName: proto.String("Foo.State"),
+			},
+			want: iterType{
+				iterTypeName: "StateIterator",
+				elemTypeName: "foopb.Foo_State",
+				elemImports:  []pbinfo.ImportSpec{{Name: "foopb", Path: "path/to/foo"}},
+			},
+		},
+		{
+			field: &descriptor.FieldDescriptorProto{
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String("Foo.ThingsEntry"),
+			},
+			want: iterType{
+				iterTypeName:  "Int64FooPairIterator",
+				elemTypeName:  "Int64FooPair",
+				elemImports:   []pbinfo.ImportSpec{{Name: "foopb", Path: "path/to/foo"}},
+				keyTypeName:   "int64",
+				valueTypeName: "*foopb.Foo",
+			},
+		},
 	} {
 		got, err := g.iterTypeOf(tst.field)
 		if err != nil {
@@ -206,4 +356,106 @@ func TestIterTypeOf(t *testing.T) {
 			t.Errorf("%d: (got=-, want=+):\n%s", i, diff)
 		}
 	}
+
+	for _, f := range []*descriptor.FieldDescriptorProto{
+		{Name: proto.String("group"), Type: typep(descriptor.FieldDescriptorProto_TYPE_GROUP)},
+		{Name: proto.String("missing"), Type: typep(descriptor.FieldDescriptorProto_TYPE_ENUM), TypeName: proto.String("Foo.Missing")},
+	} {
+		if got, err := g.iterTypeOf(f); err == nil {
+			t.Errorf("iterTypeOf(%v) = %v, expected error", f, got)
+		}
+	}
+}
+
+func TestConfiguredPagingCall(t *testing.T) {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+
+	listReq := &descriptor.DescriptorProto{
+		Name: proto.String("ListThingsRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("max_results"), Type: typep(descriptor.FieldDescriptorProto_TYPE_UINT32), Label: optional},
+			{Name: proto.String("limit"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT64), Label: optional},
+			{Name: proto.String("page_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+		},
+	}
+	listResp := &descriptor.DescriptorProto{
+		Name: proto.String("ListThingsResponse"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("next_token"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{Name: proto.String("things"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED)},
+			{Name: proto.String("unreachable"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED)},
+		},
+	}
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+	serv := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("Foo"),
+		Method: []*descriptor.MethodDescriptorProto{
+			{Name: proto.String("ListThings"), InputType: proto.String(".my.pkg.ListThingsRequest"), OutputType: proto.String(".my.pkg.ListThingsResponse")},
+			{Name: proto.String("ListThingsLimited"), InputType: proto.String(".my.pkg.ListThingsRequest"), OutputType: proto.String(".my.pkg.ListThingsResponse")},
+			{Name: proto.String("ListThingsUnsized"), InputType: proto.String(".my.pkg.ListThingsRequest"), OutputType: proto.String(".my.pkg.ListThingsResponse")},
+		},
+	}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	commonTypes(&g)
+	for _, typ := range []*descriptor.DescriptorProto{listReq, listResp} {
+		g.descInfo.Type[".my.pkg."+typ.GetName()] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+	g.descInfo.ParentFile[serv] = file
+
+	conf, err := parseGAPICConfig(strings.NewReader(`
+interfaces:
+- name: my.pkg.Foo
+  methods:
+  - name: ListThings
+    page_streaming:
+      request:
+        page_size_field: max_results
+        token_field: page_token
+      response:
+        token_field: next_token
+        resources_field: things
+  - name: ListThingsLimited
+    page_streaming:
+      request:
+        page_size_field: limit
+        token_field: page_token
+      response:
+        token_field: next_token
+        resources_field: things
+  - name: ListThingsUnsized
+    page_streaming:
+      request:
+        token_field: page_token
+      response:
+        token_field: next_token
+        resources_field: things
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.gapicConf = conf
+
+	for _, m := range serv.Method {
+		aux := auxTypes{
+			iters: map[string]*descriptor.FieldDescriptorProto{},
+		}
+		if err := g.genMethod("Foo", serv, m, &aux); err != nil {
+			t.Fatal(err)
+		}
+	}
+	diff(t, "configured_paging", g.pt.String(), filepath.Join("testdata", "configured_paging.want"))
 }
@@ -0,0 +1,143 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"strings"
+
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+)
+
+// pathTemplate is a parsed path template, as used by the google.api.http
+// and google.api.resource annotations.
+//
+// The syntax is described in google/api/http.proto:
+//
+//	Template = [ "/" ] Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	FieldPath = IDENT { "." IDENT } ;
+//	Verb     = ":" LITERAL ;
+type pathTemplate struct {
+	// Each segment is a literal, "*", or "**".
+	// Segments of variables are included; {name} is the same as {name=*}.
+	segments []string
+
+	// Variables, in the order they appear.
+	vars []pathVar
+
+	// The custom verb, without the colon. Empty if there is none.
+	verb string
+}
+
+// pathVar is a variable in a pathTemplate.
+type pathVar struct {
+	// The dot-separated path of the field the variable binds to.
+	fieldPath string
+
+	// The variable covers segments[start:end] of the template.
+	start, end int
+}
+
+// parsePathTemplate parses s as a pathTemplate.
+func parsePathTemplate(s string) (pathTemplate, error) {
+	var pt pathTemplate
+
+	rest := strings.TrimPrefix(s, "/")
+	if c := strings.LastIndexByte(rest, ':'); c >= 0 && c > strings.LastIndexByte(rest, '/') && c > strings.LastIndexByte(rest, '}') {
+		pt.verb = rest[c+1:]
+		rest = rest[:c]
+		if pt.verb == "" {
+			return pathTemplate{}, errors.User(nil, "bad path template %q: empty verb", s)
+		}
+	}
+	if rest == "" {
+		return pathTemplate{}, errors.User(nil, "bad path template %q: no segments", s)
+	}
+
+	for rest != "" {
+		if rest[0] == '{' {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return pathTemplate{}, errors.User(nil, "bad path template %q: unclosed variable", s)
+			}
+			v := rest[1:end]
+			rest = rest[end+1:]
+
+			field, sub := v, "*"
+			if eq := strings.IndexByte(v, '='); eq >= 0 {
+				field, sub = v[:eq], v[eq+1:]
+			}
+			for _, id := range strings.Split(field, ".") {
+				if !isIdent(id) {
+					return pathTemplate{}, errors.User(nil, "bad path template %q: bad variable name %q", s, field)
+				}
+			}
+
+			start := len(pt.segments)
+			for _, seg := range strings.Split(sub, "/") {
+				if err := pt.addSegment(seg); err != nil {
+					return pathTemplate{}, errors.User(err, "bad path template %q", s)
+				}
+			}
+			pt.vars = append(pt.vars, pathVar{fieldPath: field, start: start, end: len(pt.segments)})
+		} else {
+			end := strings.IndexByte(rest, '/')
+			if end < 0 {
+				end = len(rest)
+			}
+			if err := pt.addSegment(rest[:end]); err != nil {
+				return pathTemplate{}, errors.User(err, "bad path template %q", s)
+			}
+			rest = rest[end:]
+		}
+
+		if rest == "" {
+			break
+		}
+		if rest[0] != '/' || len(rest) == 1 {
+			return pathTemplate{}, errors.User(nil, "bad path template %q: expected segment after %q", s, s[:len(s)-len(rest)])
+		}
+		rest = rest[1:]
+	}
+
+	var multi int
+	for _, seg := range pt.segments {
+		if seg == "**" {
+			multi++
+		}
+	}
+	if multi > 1 {
+		return pathTemplate{}, errors.User(nil, "bad path template %q: more than one \"**\"", s)
+	}
+	return pt, nil
+}
+
+func (pt *pathTemplate) addSegment(seg string) error {
+	if seg == "" {
+		return errors.E(nil, "empty segment")
+	}
+	if strings.ContainsAny(seg, "{}=") {
+		return errors.E(nil, "unexpected character in segment %q", seg)
+	}
+	pt.segments = append(pt.segments, seg)
+	return nil
+}
+
+// isWildcard reports whether seg matches a variable number of characters.
+func isWildcard(seg string) bool {
+	return seg == "*" || seg == "**"
+}
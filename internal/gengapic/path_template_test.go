@@ -0,0 +1,83 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	for _, tst := range []struct {
+		tmpl string
+		want pathTemplate
+	}{
+		{
+			tmpl: "projects/*/topics/*",
+			want: pathTemplate{segments: []string{"projects", "*", "topics", "*"}},
+		},
+		{
+			tmpl: "/v1/{name=projects/*/topics/*}:publish",
+			want: pathTemplate{
+				segments: []string{"v1", "projects", "*", "topics", "*"},
+				vars:     []pathVar{{fieldPath: "name", start: 1, end: 5}},
+				verb:     "publish",
+			},
+		},
+		{
+			tmpl: "/v1/{parent}/things/{thing.id}",
+			want: pathTemplate{
+				segments: []string{"v1", "*", "things", "*"},
+				vars: []pathVar{
+					{fieldPath: "parent", start: 1, end: 2},
+					{fieldPath: "thing.id", start: 3, end: 4},
+				},
+			},
+		},
+		{
+			tmpl: "buckets/*/objects/**",
+			want: pathTemplate{segments: []string{"buckets", "*", "objects", "**"}},
+		},
+	} {
+		got, err := parsePathTemplate(tst.tmpl)
+		if err != nil {
+			t.Errorf("parsePathTemplate(%q): %v", tst.tmpl, err)
+			continue
+		}
+		if diff := cmp.Diff(got, tst.want, cmp.AllowUnexported(pathTemplate{}, pathVar{})); diff != "" {
+			t.Errorf("parsePathTemplate(%q): got(-),want(+):\n%s", tst.tmpl, diff)
+		}
+	}
+}
+
+func TestParsePathTemplateError(t *testing.T) {
+	for _, tmpl := range []string{
+		"",
+		"/",
+		"projects/*/",
+		"projects//topics",
+		"projects/*:",
+		"projects/{project",
+		"projects/{1project}",
+		"projects/{project=}",
+		"a/**/b/**",
+		"a/{b=**}/**",
+	} {
+		if _, err := parsePathTemplate(tmpl); err == nil {
+			t.Errorf("parsePathTemplate(%q): expected error", tmpl)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// resourcePath describes the helper functions generated for a resource path template.
+type resourcePath struct {
+	// The path function is named funcName, the parse function "Parse"+funcName.
+	funcName string
+
+	// The colloquial name of the resource, used in docs.
+	baseName string
+
+	template string
+	pt       pathTemplate
+
+	// Parameters of the path function, in the order they appear in the path.
+	// Each named variable and each wildcard outside of variables is a parameter.
+	// The ith parameter covers pt.segments[ranges[i][0]:ranges[i][1]].
+	params []string
+	ranges [][2]int
+}
+
+// pathFuncImports are the packages used by the generated functions.
+var pathFuncImports = []pbinfo.ImportSpec{
+	{Path: "fmt"},
+	{Path: "strings"},
+}
+
+// reservedPathParams are identifiers used by the generated functions that parameters must not shadow:
+// their variables and the packages they use.
+var reservedPathParams = func() map[string]bool {
+	r := map[string]bool{
+		"path": true,
+		"segs": true,
+		"err":  true,
+	}
+	for _, imp := range pathFuncImports {
+		r[imp.Path[strings.LastIndexByte(imp.Path, '/')+1:]] = true
+	}
+	return r
+}()
+
+// newResourcePath parses the path template of a resource.
+func newResourcePath(template, baseName string) (resourcePath, error) {
+	pt, err := parsePathTemplate(template)
+	if err != nil {
+		return resourcePath{}, err
+	}
+	if pt.verb != "" {
+		return resourcePath{}, errors.User(nil, "resource path %q must not have a verb", template)
+	}
+
+	rp := resourcePath{
+		baseName: baseName,
+		template: template,
+		pt:       pt,
+	}
+
+	used := map[string]bool{}
+	addParam := func(name string, start, end int) {
+		if token.Lookup(name).IsKeyword() || reservedPathParams[name] {
+			name += "ID"
+		}
+		if used[name] {
+			for i := 2; ; i++ {
+				if n := name + strconv.Itoa(i); !used[n] {
+					name = n
+					break
+				}
+			}
+		}
+		used[name] = true
+		rp.params = append(rp.params, name)
+		rp.ranges = append(rp.ranges, [2]int{start, end})
+	}
+
+	vi := 0
+	for i := 0; i < len(pt.segments); i++ {
+		if vi < len(pt.vars) && pt.vars[vi].start == i {
+			v := pt.vars[vi]
+			vi++
+			fp := v.fieldPath[strings.LastIndexByte(v.fieldPath, '.')+1:]
+			addParam(lowerFirst(naming.CamelCase(fp)), v.start, v.end)
+			i = v.end - 1
+			continue
+		}
+
+		if !isWildcard(pt.segments[i]) {
+			continue
+		}
+		// Name unnamed wildcards after the collection they are in,
+		// so "projects/*" gives "project".
+		name := "arg"
+		if i > 0 && !isWildcard(pt.segments[i-1]) {
+			name = lowerFirst(naming.CamelCase(singular(identChars(pt.segments[i-1]))))
+		}
+		addParam(name, i, i+1)
+	}
+
+	if len(rp.params) == 0 {
+		rp.funcName = upperFirst(naming.CamelCase(identChars(baseName))) + "Path"
+	} else {
+		var sb strings.Builder
+		for _, prm := range rp.params {
+			sb.WriteString(upperFirst(prm))
+		}
+		sb.WriteString("Path")
+		rp.funcName = sb.String()
+	}
+	return rp, nil
+}
+
+// identChars replaces characters that cannot be in an identifier with underscores.
+func identChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// singular returns a guess of the singular form of the English noun s.
+func singular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "sses"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss"):
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// collectResourcePaths finds the resource annotations on the fields of messages in files.
+// Paths are deduplicated by function name; it is an error for different templates
+// to result in the same name.
+func collectResourcePaths(files []*descriptor.FileDescriptorProto) ([]resourcePath, error) {
+	var paths []resourcePath
+	seen := map[string]string{}
+
+	add := func(res *annotations.Resource, defaultBase string) error {
+		base := res.GetBaseName()
+		if base == "" {
+			base = defaultBase
+		}
+		rp, err := newResourcePath(res.GetPath(), base)
+		if err != nil {
+			return err
+		}
+		if t, ok := seen[rp.funcName]; ok {
+			if t != rp.template {
+				return errors.User(nil, "resource paths %q and %q both generate function %s", t, rp.template, rp.funcName)
+			}
+			return nil
+		}
+		seen[rp.funcName] = rp.template
+		paths = append(paths, rp)
+		return nil
+	}
+
+	var addMsg func(*descriptor.DescriptorProto) error
+	addMsg = func(msg *descriptor.DescriptorProto) error {
+		for _, f := range msg.GetField() {
+			if f.GetOptions() == nil {
+				continue
+			}
+
+			eRes, err := proto.GetExtension(f.GetOptions(), annotations.E_Resource)
+			if err == nil {
+				if err := add(eRes.(*annotations.Resource), msg.GetName()); err != nil {
+					return errors.E(err, "field: %s.%s", msg.GetName(), f.GetName())
+				}
+			} else if err != proto.ErrMissingExtension {
+				return errors.E(err, "cannot read resource annotation of %s.%s", msg.GetName(), f.GetName())
+			}
+
+			eSet, err := proto.GetExtension(f.GetOptions(), annotations.E_ResourceSet)
+			if err == nil {
+				set := eSet.(*annotations.ResourceSet)
+				base := set.GetBaseName()
+				if base == "" {
+					base = msg.GetName()
+				}
+				for _, res := range set.GetResources() {
+					if err := add(res, base); err != nil {
+						return errors.E(err, "field: %s.%s", msg.GetName(), f.GetName())
+					}
+				}
+			} else if err != proto.ErrMissingExtension {
+				return errors.E(err, "cannot read resource_set annotation of %s.%s", msg.GetName(), f.GetName())
+			}
+		}
+		for _, nested := range msg.GetNestedType() {
+			if err := addMsg(nested); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, f := range files {
+		for _, msg := range f.GetMessageType() {
+			if err := addMsg(msg); err != nil {
+				return nil, errors.E(err, "file: %s", f.GetName())
+			}
+		}
+	}
+	return paths, nil
+}
+
+// genPathFuncs generates helper functions to build and parse the resource paths.
+func (g *generator) genPathFuncs(paths []resourcePath) {
+	for _, rp := range paths {
+		g.pathFunc(rp)
+		if len(rp.params) > 0 {
+			g.parsePathFunc(rp)
+		}
+	}
+}
+
+func (g *generator) pathFunc(rp resourcePath) {
+	p := g.printf
+
+	// Concatenate the literals between parameters, so
+	// "projects/*/topics/*" gives "projects/" + project + "/topics/" + topic.
+	var parts []string
+	var lit strings.Builder
+	pi := 0
+	for i := 0; i < len(rp.pt.segments); i++ {
+		if i > 0 {
+			lit.WriteByte('/')
+		}
+		if pi < len(rp.ranges) && rp.ranges[pi][0] == i {
+			if lit.Len() > 0 {
+				parts = append(parts, strconv.Quote(lit.String()))
+				lit.Reset()
+			}
+			parts = append(parts, rp.params[pi])
+			i = rp.ranges[pi][1] - 1
+			pi++
+			continue
+		}
+		lit.WriteString(rp.pt.segments[i])
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, strconv.Quote(lit.String()))
+	}
+
+	var params string
+	if len(rp.params) > 0 {
+		params = strings.Join(rp.params, ", ") + " string"
+	}
+
+	p("// %s returns the path of the %s resource.", rp.funcName, rp.baseName)
+	p("// The path has the form %q.", rp.template)
+	p("func %s(%s) string {", rp.funcName, params)
+	p("  return %s", strings.Join(parts, " + "))
+	p("}")
+	p("")
+}
+
+func (g *generator) parsePathFunc(rp resourcePath) {
+	p := g.printf
+
+	segs := rp.pt.segments
+	n := len(segs)
+	multi := -1
+	for i, seg := range segs {
+		if seg == "**" {
+			multi = i
+		}
+	}
+
+	// index returns the expression for the index of the element in segs
+	// corresponding to the ith segment of the template.
+	// After "**", segments are counted from the end.
+	index := func(i int) string {
+		if multi < 0 || i <= multi {
+			return strconv.Itoa(i)
+		}
+		if i == n {
+			return ""
+		}
+		return fmt.Sprintf("len(segs)-%d", n-i)
+	}
+
+	var conds []string
+	if multi < 0 {
+		conds = append(conds, fmt.Sprintf("len(segs) != %d", n))
+	} else {
+		conds = append(conds, fmt.Sprintf("len(segs) < %d", n))
+	}
+	for i, seg := range segs {
+		switch seg {
+		case "**":
+		case "*":
+			conds = append(conds, fmt.Sprintf("segs[%s] == \"\"", index(i)))
+		default:
+			conds = append(conds, fmt.Sprintf("segs[%s] != %q", index(i), seg))
+		}
+	}
+
+	var vals, zeros []string
+	for _, r := range rp.ranges {
+		if r[1]-r[0] == 1 && segs[r[0]] != "**" {
+			vals = append(vals, fmt.Sprintf("segs[%s]", index(r[0])))
+		} else {
+			vals = append(vals, fmt.Sprintf("strings.Join(segs[%s:%s], \"/\")", index(r[0]), index(r[1])))
+		}
+		zeros = append(zeros, `""`)
+	}
+
+	names := strings.Join(rp.params, ", ")
+	if n := len(rp.params); n > 1 {
+		names = strings.Join(rp.params[:n-1], ", ") + " and " + rp.params[n-1]
+	}
+	p("// Parse%s parses a path of the %s resource, returning its %s.", rp.funcName, rp.baseName, names)
+	p("// The path must have the form %q.", rp.template)
+	p("func Parse%s(path string) (%s string, err error) {", rp.funcName, strings.Join(rp.params, ", "))
+	p("  segs := strings.Split(path, \"/\")")
+	p("  if %s {", strings.Join(conds, " || "))
+	p("    return %s, fmt.Errorf(\"path %%q does not have the form %%q\", path, %q)", strings.Join(zeros, ", "), rp.template)
+	p("  }")
+	p("  return %s, nil", strings.Join(vals, ", "))
+	p("}")
+	p("")
+
+	for _, imp := range pathFuncImports {
+		g.imports[imp] = true
+	}
+}
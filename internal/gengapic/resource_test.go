@@ -0,0 +1,100 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func resourceField(name string, ext *proto.ExtensionDesc, v interface{}) *descriptor.FieldDescriptorProto {
+	opts := &descriptor.FieldOptions{}
+	if err := proto.SetExtension(opts, ext, v); err != nil {
+		panic(err)
+	}
+	return &descriptor.FieldDescriptorProto{Name: proto.String(name), Options: opts}
+}
+
+func TestPathFuncs(t *testing.T) {
+	file := &descriptor.FileDescriptorProto{
+		Name: proto.String("foo.proto"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Topic"),
+				Field: []*descriptor.FieldDescriptorProto{
+					resourceField("name", annotations.E_Resource, &annotations.Resource{Path: "projects/*/topics/*"}),
+				},
+				NestedType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Nested"),
+						Field: []*descriptor.FieldDescriptorProto{
+							// Same template as Topic.name, only generated once.
+							resourceField("topic", annotations.E_Resource, &annotations.Resource{Path: "projects/*/topics/*"}),
+						},
+					},
+				},
+			},
+			{
+				Name: proto.String("Object"),
+				Field: []*descriptor.FieldDescriptorProto{
+					resourceField("name", annotations.E_ResourceSet, &annotations.ResourceSet{
+						BaseName: "Object",
+						Resources: []*annotations.Resource{
+							{Path: "buckets/{bucket}/objects/**"},
+							{Path: "types/*/{name=sub/*}/*"},
+							{Path: "deleted-objects", BaseName: "deleted_objects"},
+							// Variables named like the packages used by the functions.
+							{Path: "formats/{fmt}/strings/{strings}", BaseName: "formatted"},
+						},
+					}),
+				},
+			},
+		},
+	}
+
+	paths, err := collectResourcePaths([]*descriptor.FileDescriptorProto{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	g.genPathFuncs(paths)
+	diff(t, "path_funcs", g.pt.String(), filepath.Join("testdata", "path_funcs.want"))
+}
+
+func TestResourcePathError(t *testing.T) {
+	for _, tst := range [][]string{
+		{"projects/*:get"},
+		{"projects/{project}/**", "projects/*/*"},
+		{"projects/{project}", "projects/{project=**}"},
+	} {
+		var fields []*descriptor.FieldDescriptorProto
+		for _, p := range tst {
+			fields = append(fields, resourceField("name", annotations.E_Resource, &annotations.Resource{Path: p}))
+		}
+		file := &descriptor.FileDescriptorProto{
+			MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Foo"), Field: fields}},
+		}
+		if _, err := collectResourcePaths([]*descriptor.FileDescriptorProto{file}); err == nil {
+			t.Errorf("collectResourcePaths(%q): expected error", tst)
+		}
+	}
+}
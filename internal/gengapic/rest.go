@@ -0,0 +1,609 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+const fieldMaskType = ".google.protobuf.FieldMask"
+
+// httpRule returns the google.api.http annotation of m, or nil if m is not annotated.
+func httpRule(m *descriptor.MethodDescriptorProto) (*annotations.HttpRule, error) {
+	if m.GetOptions() == nil {
+		return nil, nil
+	}
+	eHttp, err := proto.GetExtension(m.GetOptions(), annotations.E_Http)
+	if err == proto.ErrMissingExtension {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.E(err, "cannot read HTTP annotation")
+	}
+	return eHttp.(*annotations.HttpRule), nil
+}
+
+// httpVerbPath reports the HTTP method and the path template of rule.
+func httpVerbPath(rule *annotations.HttpRule) (string, string, error) {
+	switch pat := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return "GET", pat.Get, nil
+	case *annotations.HttpRule_Put:
+		return "PUT", pat.Put, nil
+	case *annotations.HttpRule_Post:
+		return "POST", pat.Post, nil
+	case *annotations.HttpRule_Delete:
+		return "DELETE", pat.Delete, nil
+	case *annotations.HttpRule_Patch:
+		return "PATCH", pat.Patch, nil
+	case *annotations.HttpRule_Custom:
+		return pat.Custom.GetKind(), pat.Custom.GetPath(), nil
+	}
+	return "", "", errors.User(nil, "HTTP annotation has no pattern")
+}
+
+// restClientName reports the name of the unexported type implementing the gRPC client interface over REST.
+func restClientName(servName string) string {
+	return lowerFirst(servName + "RESTClient")
+}
+
+// genRESTClient generates a REST client for serv.
+// The client implements the gRPC client interface of serv, so the methods of the
+// client wrapper, including retries and paging, work the same over both transports.
+func (g *generator) genRESTClient(serv *descriptor.ServiceDescriptorProto, servName string) error {
+	p := g.printf
+
+	imp, err := g.importSpec(serv)
+	if err != nil {
+		return err
+	}
+
+	// defaultRESTClientOptions
+	{
+		eHost, err := proto.GetExtension(serv.Options, annotations.E_DefaultHost)
+		if err != nil {
+			return errors.At(g.pos(serv), errors.User(err, "cannot read default host"), "service %s", serv.GetName())
+		}
+
+		p("func default%sRESTClientOptions() []option.ClientOption {", servName)
+		p("  return []option.ClientOption{")
+		p(`    option.WithEndpoint("https://%s"),`, *eHost.(*string))
+		p("    option.WithScopes(DefaultAuthScopes()...),")
+		p("  }")
+		p("}")
+		p("")
+
+		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/api/option"}] = true
+	}
+
+	// Client constructor
+	{
+		clientName := naming.Snake(serv.GetName())
+		clientName = strings.Replace(clientName, "_", " ", -1)
+
+		p("// New%sRESTClient creates a new %s client that sends requests as JSON over HTTP/1.1", servName, clientName)
+		p("// instead of gRPC. The client uses the HTTP mapping of the methods, so it can be")
+		p("// used where HTTP/2 is not available.")
+		p("//")
+		p("// Streaming methods, methods without an HTTP mapping, and methods returning long-running")
+		p("// operations are not supported and return an error with code Unimplemented.")
+		p("//")
+		g.comment(g.comments[serv])
+		p("func New%[1]sRESTClient(ctx context.Context, opts ...option.ClientOption) (*%[1]sClient, error) {", servName)
+		p("  httpClient, endpoint, err := htransport.NewClient(ctx, append(default%sRESTClientOptions(), opts...)...)", servName)
+		p("  if err != nil {")
+		p("    return nil, err")
+		p("  }")
+		p("  c := &%sClient{", servName)
+		p("    CallOptions: default%sCallOptions(),", servName)
+		p("")
+		p("    %s: &%s{", g.grpcClientField(servName), restClientName(servName))
+		p("      httpClient: httpClient,")
+		p(`      endpoint:   strings.TrimSuffix(endpoint, "/"),`)
+		p("    },")
+		p("  }")
+		p("  c.setGoogleClientInfo()")
+		p("  return c, nil")
+		p("}")
+		p("")
+
+		g.imports[pbinfo.ImportSpec{Name: "htransport", Path: "google.golang.org/api/transport/http"}] = true
+		g.imports[pbinfo.ImportSpec{Path: "golang.org/x/net/context"}] = true
+		g.imports[pbinfo.ImportSpec{Path: "strings"}] = true
+	}
+
+	// REST client type
+	{
+		p("// %s implements %s.%sClient by sending JSON requests over HTTP/1.1.", restClientName(servName), imp.Name, serv.GetName())
+		p("type %s struct {", restClientName(servName))
+		p("  httpClient *http.Client")
+		p("")
+		p("  // The endpoint of the service, without a trailing slash.")
+		p("  endpoint string")
+		p("}")
+		p("")
+
+		g.imports[pbinfo.ImportSpec{Path: "net/http"}] = true
+	}
+
+	for _, m := range serv.GetMethod() {
+		if err := g.restMethod(servName, serv, m); err != nil {
+			return errors.At(g.pos(m), err, "method %s", m.GetName())
+		}
+	}
+	return nil
+}
+
+// restMethod generates the implementation of method m of the gRPC client interface of serv.
+func (g *generator) restMethod(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
+	p := g.printf
+
+	servSpec, err := g.importSpec(serv)
+	if err != nil {
+		return err
+	}
+
+	inType := g.descInfo.Type[m.GetInputType()]
+	inSpec, err := g.importSpec(inType)
+	if err != nil {
+		return err
+	}
+
+	unimplemented := func(reason string) {
+		p("  return nil, status.Error(codes.Unimplemented, %q)", m.GetName()+" "+reason)
+		p("}")
+		p("")
+
+		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc/codes"}] = true
+		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc/status"}] = true
+	}
+
+	recv := restClientName(servName)
+	streamType := fmt.Sprintf("%s.%s_%sClient", servSpec.Name, serv.GetName(), m.GetName())
+	switch {
+	case m.GetClientStreaming():
+		p("func (c *%s) %s(ctx context.Context, opts ...grpc.CallOption) (%s, error) {", recv, m.GetName(), streamType)
+		unimplemented("is a streaming method, not supported over REST")
+		g.imports[servSpec] = true
+		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc"}] = true
+		return nil
+	case m.GetServerStreaming():
+		p("func (c *%s) %s(ctx context.Context, req *%s.%s, opts ...grpc.CallOption) (%s, error) {",
+			recv, m.GetName(), inSpec.Name, inType.GetName(), streamType)
+		unimplemented("is a streaming method, not supported over REST")
+		g.imports[inSpec] = true
+		g.imports[servSpec] = true
+		g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc"}] = true
+		return nil
+	}
+
+	outType := g.descInfo.Type[m.GetOutputType()]
+	outSpec, err := g.importSpec(outType)
+	if err != nil {
+		return err
+	}
+
+	p("func (c *%s) %s(ctx context.Context, req *%s.%s, opts ...grpc.CallOption) (*%s.%s, error) {",
+		recv, m.GetName(), inSpec.Name, inType.GetName(), outSpec.Name, outType.GetName())
+
+	g.imports[inSpec] = true
+	g.imports[outSpec] = true
+	g.imports[pbinfo.ImportSpec{Path: "golang.org/x/net/context"}] = true
+	g.imports[pbinfo.ImportSpec{Path: "google.golang.org/grpc"}] = true
+
+	if m.GetOutputType() == lroType {
+		unimplemented("returns a long-running operation, not supported over REST")
+		return nil
+	}
+
+	rule, err := httpRule(m)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		unimplemented("has no HTTP mapping")
+		return nil
+	}
+
+	verb, tmpl, err := httpVerbPath(rule)
+	if err != nil {
+		return err
+	}
+	pt, err := parsePathTemplate(tmpl)
+	if err != nil {
+		return err
+	}
+
+	inMsg, ok := inType.(*descriptor.DescriptorProto)
+	if !ok {
+		return errors.E(nil, "cannot find message type %q, malformed descriptor?", m.GetInputType())
+	}
+
+	// Fields sent in the path or in the body are not sent as query parameters.
+	sent := map[string]bool{}
+
+	path, err := g.restPath(inMsg, pt)
+	if err != nil {
+		return err
+	}
+	p("path := %s", path)
+	for _, v := range pt.vars {
+		sent[v.fieldPath] = true
+	}
+
+	body := "nil"
+	switch b := rule.GetBody(); b {
+	case "":
+	case "*":
+		body = "req"
+	default:
+		f := findField(inMsg, b)
+		if f == nil {
+			return errors.User(nil, "message %q has no body field %q", inMsg.GetName(), b)
+		}
+		if f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			return errors.User(nil, "body field %q must be a non-repeated message", b)
+		}
+		typ, imp, err := g.goTypeName(g.descInfo.Type[f.GetTypeName()])
+		if err != nil {
+			return err
+		}
+		p("body := req.Get%s()", naming.CamelCase(b))
+		p("if body == nil {")
+		p("  body = &%s{}", typ)
+		p("}")
+		body = "body"
+		sent[b] = true
+		g.imports[imp] = true
+	}
+
+	params := "nil"
+	if body != "req" {
+		p("params := url.Values{}")
+		if err := g.restQueryParams(inMsg, "req", "", "", sent, map[*descriptor.DescriptorProto]bool{}); err != nil {
+			return err
+		}
+		params = "params"
+		g.imports[pbinfo.ImportSpec{Path: "net/url"}] = true
+	}
+
+	p("resp := &%s.%s{}", outSpec.Name, outType.GetName())
+	p("if err := restDo(ctx, c.httpClient, %q, c.endpoint+path, %s, %s, resp); err != nil {", verb, params, body)
+	p("  return nil, err")
+	p("}")
+	p("return resp, nil")
+	p("}")
+	p("")
+	return nil
+}
+
+// restPath returns the Go expression for the path of a request to the path template pt,
+// with the variables expanded from the fields of the request message msg.
+func (g *generator) restPath(msg *descriptor.DescriptorProto, pt pathTemplate) (string, error) {
+	var parts []string
+	var lit strings.Builder
+	vi := 0
+	for i := 0; i < len(pt.segments); i++ {
+		lit.WriteByte('/')
+		if vi >= len(pt.vars) || pt.vars[vi].start != i {
+			lit.WriteString(pt.segments[i])
+			continue
+		}
+
+		v := pt.vars[vi]
+		vi++
+		i = v.end - 1
+
+		expr, err := g.restPathVar(msg, pt, v)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, strconv.Quote(lit.String()), expr)
+		lit.Reset()
+	}
+	if pt.verb != "" {
+		lit.WriteString(":" + pt.verb)
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, strconv.Quote(lit.String()))
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+// restPathVar returns the Go expression for the escaped value of path variable v.
+func (g *generator) restPathVar(msg *descriptor.DescriptorProto, pt pathTemplate, v pathVar) (string, error) {
+	expr, err := g.pathVarValue(msg, v.fieldPath)
+	if err != nil {
+		return "", err
+	}
+
+	g.imports[pbinfo.ImportSpec{Path: "net/url"}] = true
+	if v.end-v.start == 1 && pt.segments[v.start] == "*" {
+		return fmt.Sprintf("url.PathEscape(%s)", expr), nil
+	}
+	// Variables matching multiple segments keep their slashes.
+	g.imports[pbinfo.ImportSpec{Path: "strings"}] = true
+	return fmt.Sprintf(`strings.Replace(url.PathEscape(%s), "%%2F", "/", -1)`, expr), nil
+}
+
+// pathVarValue returns the Go expression for the string value of the field at fieldPath
+// in req, a request of message type msg.
+func (g *generator) pathVarValue(msg *descriptor.DescriptorProto, fieldPath string) (string, error) {
+	expr := "req"
+	elems := strings.Split(fieldPath, ".")
+	var f *descriptor.FieldDescriptorProto
+	for i, e := range elems {
+		if f = findField(msg, e); f == nil {
+			return "", errors.User(nil, "message %q has no field %q", msg.GetName(), e)
+		}
+		expr += ".Get" + naming.CamelCase(e) + "()"
+		if i == len(elems)-1 {
+			break
+		}
+
+		if f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			return "", errors.User(nil, "field %q in path %q must be a non-repeated message", e, fieldPath)
+		}
+		var ok bool
+		if msg, ok = g.descInfo.Type[f.GetTypeName()].(*descriptor.DescriptorProto); !ok {
+			return "", errors.E(nil, "cannot find message type %q, malformed descriptor?", f.GetTypeName())
+		}
+	}
+
+	if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return "", errors.At(g.pos(f), errors.User(nil, "must not be repeated"), "path variable %s", fieldPath)
+	}
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return expr, nil
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return "", errors.At(g.pos(f), errors.User(nil, "must be a string, number, bool or enum"), "path variable %s", fieldPath)
+	}
+	g.imports[pbinfo.ImportSpec{Path: "fmt"}] = true
+	return fmt.Sprintf("fmt.Sprint(%s)", expr), nil
+}
+
+// restQueryParams generates code adding the fields of msg, except the ones in sent, to params.
+// expr is the Go expression of msg, path is the field path of msg in the request,
+// and jsonPath is the same path, using the JSON names of the fields.
+// Messages in visiting are not expanded again, to stop recursion.
+func (g *generator) restQueryParams(msg *descriptor.DescriptorProto, expr, path, jsonPath string, sent map[string]bool, visiting map[*descriptor.DescriptorProto]bool) error {
+	p := g.printf
+
+	visiting[msg] = true
+	defer delete(visiting, msg)
+
+	for _, f := range msg.GetField() {
+		fPath := path + f.GetName()
+		if sent[fPath] {
+			continue
+		}
+
+		jsonName := f.GetJsonName()
+		if jsonName == "" {
+			jsonName = naming.JSONName(f.GetName())
+		}
+		jsonName = jsonPath + jsonName
+		fExpr := fmt.Sprintf("%s.Get%s()", expr, naming.CamelCase(f.GetName()))
+
+		if f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+				// Repeated messages and maps cannot be sent as query parameters.
+				continue
+			}
+			if f.GetTypeName() == fieldMaskType {
+				p("if v := %s; v != nil {", fExpr)
+				p("  params.Add(%q, strings.Join(v.GetPaths(), \",\"))", jsonName)
+				p("}")
+				g.imports[pbinfo.ImportSpec{Path: "strings"}] = true
+				continue
+			}
+			if strings.HasPrefix(f.GetTypeName(), ".google.protobuf.") {
+				// Other well-known types have special JSON representations.
+				continue
+			}
+
+			sub, ok := g.descInfo.Type[f.GetTypeName()].(*descriptor.DescriptorProto)
+			if !ok {
+				return errors.E(nil, "cannot find message type %q, malformed descriptor?", f.GetTypeName())
+			}
+			if visiting[sub] {
+				continue
+			}
+			if err := g.restQueryParams(sub, fExpr, fPath+".", jsonName+".", sent, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP {
+			continue
+		}
+
+		if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			p("for _, v := range %s {", fExpr)
+			p("  params.Add(%q, %s)", jsonName, g.queryValue(f, "v"))
+			p("}")
+			continue
+		}
+
+		switch f.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_BOOL:
+			p("if %s {", fExpr)
+			p("  params.Add(%q, \"true\")", jsonName)
+			p("}")
+		case descriptor.FieldDescriptorProto_TYPE_STRING:
+			p("if v := %s; v != \"\" {", fExpr)
+			p("  params.Add(%q, v)", jsonName)
+			p("}")
+		case descriptor.FieldDescriptorProto_TYPE_BYTES:
+			p("if v := %s; len(v) > 0 {", fExpr)
+			p("  params.Add(%q, %s)", jsonName, g.queryValue(f, "v"))
+			p("}")
+		default:
+			p("if v := %s; v != 0 {", fExpr)
+			p("  params.Add(%q, %s)", jsonName, g.queryValue(f, "v"))
+			p("}")
+		}
+	}
+	return nil
+}
+
+// queryValue returns the Go expression for the query parameter value of v, a value of scalar field f.
+func (g *generator) queryValue(f *descriptor.FieldDescriptorProto, v string) string {
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return v
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		g.imports[pbinfo.ImportSpec{Path: "strconv"}] = true
+		return fmt.Sprintf("strconv.FormatBool(%s)", v)
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		g.imports[pbinfo.ImportSpec{Path: "encoding/base64"}] = true
+		return fmt.Sprintf("base64.StdEncoding.EncodeToString(%s)", v)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return v + ".String()"
+	}
+	g.imports[pbinfo.ImportSpec{Path: "fmt"}] = true
+	return fmt.Sprintf("fmt.Sprint(%s)", v)
+}
+
+// genRESTHelpers generates the functions shared by the REST clients of all services.
+func (g *generator) genRESTHelpers() {
+	p := g.printf
+
+	p("// restDo sends an HTTP request to url with the JSON encoding of body, if not nil,")
+	p("// and decodes the JSON response into resp.")
+	p("// Errors, including unsuccessful HTTP statuses, are returned as gRPC status errors.")
+	p("func restDo(ctx context.Context, client *http.Client, method, u string, params url.Values, body, resp proto.Message) error {")
+	p("  var reqBody io.Reader")
+	p("  if body != nil {")
+	p("    var buf bytes.Buffer")
+	p("    if err := (&jsonpb.Marshaler{}).Marshal(&buf, body); err != nil {")
+	p("      return status.Error(codes.Internal, err.Error())")
+	p("    }")
+	p("    reqBody = &buf")
+	p("  }")
+	p("  if len(params) > 0 {")
+	p(`    u += "?" + params.Encode()`)
+	p("  }")
+	p("")
+	p("  httpReq, err := http.NewRequest(method, u, reqBody)")
+	p("  if err != nil {")
+	p("    return status.Error(codes.Internal, err.Error())")
+	p("  }")
+	p("  httpReq = httpReq.WithContext(ctx)")
+	p("  if body != nil {")
+	p(`    httpReq.Header.Set("Content-Type", "application/json")`)
+	p("  }")
+	p("  if md, ok := metadata.FromOutgoingContext(ctx); ok {")
+	p("    for k, vs := range md {")
+	p("      for _, v := range vs {")
+	p("        httpReq.Header.Add(k, v)")
+	p("      }")
+	p("    }")
+	p("  }")
+	p("")
+	p("  httpResp, err := client.Do(httpReq)")
+	p("  if err != nil {")
+	p("    switch ctx.Err() {")
+	p("    case context.Canceled:")
+	p("      return status.Error(codes.Canceled, err.Error())")
+	p("    case context.DeadlineExceeded:")
+	p("      return status.Error(codes.DeadlineExceeded, err.Error())")
+	p("    }")
+	p("    return status.Error(codes.Unavailable, err.Error())")
+	p("  }")
+	p("  defer httpResp.Body.Close()")
+	p("")
+	p("  data, err := ioutil.ReadAll(httpResp.Body)")
+	p("  if err != nil {")
+	p("    return status.Error(codes.Unavailable, err.Error())")
+	p("  }")
+	p("  if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {")
+	p("    msg := string(data)")
+	p("    var e struct {")
+	p("      Error struct {")
+	p("        Message string `json:\"message\"`")
+	p("      } `json:\"error\"`")
+	p("    }")
+	p(`    if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {`)
+	p("      msg = e.Error.Message")
+	p("    }")
+	p("    return status.Error(httpStatusCode(httpResp.StatusCode), msg)")
+	p("  }")
+	p("  if len(data) == 0 {")
+	p("    return nil")
+	p("  }")
+	p("  if err := (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(data), resp); err != nil {")
+	p("    return status.Error(codes.Internal, err.Error())")
+	p("  }")
+	p("  return nil")
+	p("}")
+	p("")
+
+	p("// httpStatusCode returns the gRPC code corresponding to an unsuccessful HTTP status,")
+	p("// following the mapping documented in google/rpc/code.proto.")
+	p("func httpStatusCode(s int) codes.Code {")
+	p("  switch s {")
+	for _, c := range []struct{ status, code string }{
+		{"http.StatusBadRequest", "InvalidArgument"},
+		{"http.StatusUnauthorized", "Unauthenticated"},
+		{"http.StatusForbidden", "PermissionDenied"},
+		{"http.StatusNotFound", "NotFound"},
+		{"http.StatusConflict", "Aborted"},
+		{"http.StatusPreconditionFailed", "FailedPrecondition"},
+		{"http.StatusRequestedRangeNotSatisfiable", "OutOfRange"},
+		{"http.StatusTooManyRequests", "ResourceExhausted"},
+		{"499", "Canceled"},
+		{"http.StatusNotImplemented", "Unimplemented"},
+		{"http.StatusServiceUnavailable", "Unavailable"},
+		{"http.StatusGatewayTimeout", "DeadlineExceeded"},
+	} {
+		p("case %s:", c.status)
+		p("  return codes.%s", c.code)
+	}
+	p("  }")
+	p("  if s >= 500 {")
+	p("    return codes.Internal")
+	p("  }")
+	p("  return codes.Unknown")
+	p("}")
+	p("")
+
+	for _, imp := range []pbinfo.ImportSpec{
+		{Path: "bytes"},
+		{Path: "encoding/json"},
+		{Path: "io"},
+		{Path: "io/ioutil"},
+		{Path: "net/http"},
+		{Path: "net/url"},
+		{Path: "github.com/golang/protobuf/jsonpb"},
+		{Path: "github.com/golang/protobuf/proto"},
+		{Path: "golang.org/x/net/context"},
+		{Path: "google.golang.org/grpc/codes"},
+		{Path: "google.golang.org/grpc/metadata"},
+		{Path: "google.golang.org/grpc/status"},
+	} {
+		g.imports[imp] = true
+	}
+}
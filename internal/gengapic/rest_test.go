@@ -0,0 +1,380 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	protocgengo "github.com/golang/protobuf/protoc-gen-go/generator"
+	_ "github.com/golang/protobuf/protoc-gen-go/grpc"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// restService returns a service whose methods cover the HTTP mappings the REST client supports.
+// The types are registered in g.
+func restService(g *generator) *descriptor.ServiceDescriptorProto {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+	repeated := labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED)
+
+	thing := &descriptor.DescriptorProto{
+		Name: proto.String("Thing"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("name"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{Name: proto.String("size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT64), Label: optional},
+		},
+	}
+	filter := &descriptor.DescriptorProto{
+		Name: proto.String("Filter"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("query"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{Name: proto.String("raw"), Type: typep(descriptor.FieldDescriptorProto_TYPE_BYTES), Label: optional},
+			{Name: proto.String("exact"), Type: typep(descriptor.FieldDescriptorProto_TYPE_BOOL), Label: optional},
+		},
+	}
+	getReq := &descriptor.DescriptorProto{
+		Name: proto.String("GetThingRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("name"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{Name: proto.String("view"), Type: typep(descriptor.FieldDescriptorProto_TYPE_ENUM), TypeName: proto.String(".my.pkg.View"), Label: optional},
+			{Name: proto.String("page_size"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT32), Label: optional},
+			{Name: proto.String("ids"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT64), Label: repeated},
+			{Name: proto.String("tags"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: repeated},
+			{Name: proto.String("filter"), Type: typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE), TypeName: proto.String(".my.pkg.Filter"), Label: optional},
+			{Name: proto.String("things"), Type: typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE), TypeName: proto.String(".my.pkg.Thing"), Label: repeated},
+		},
+	}
+	updateReq := &descriptor.DescriptorProto{
+		Name: proto.String("UpdateThingRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("thing"), Type: typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE), TypeName: proto.String(".my.pkg.Thing"), Label: optional},
+			{Name: proto.String("update_mask"), Type: typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE), TypeName: proto.String(fieldMaskType), Label: optional},
+		},
+	}
+
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+	}
+
+	commonTypes(g)
+	for _, typ := range []*descriptor.DescriptorProto{thing, filter, getReq, updateReq} {
+		g.descInfo.Type[".my.pkg."+typ.GetName()] = typ
+		g.descInfo.ParentFile[typ] = file
+	}
+
+	httpOpts := func(rule *annotations.HttpRule) *descriptor.MethodOptions {
+		opts := &descriptor.MethodOptions{}
+		if err := proto.SetExtension(opts, annotations.E_Http, rule); err != nil {
+			panic(err)
+		}
+		return opts
+	}
+
+	serv := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("Foo"),
+		Method: []*descriptor.MethodDescriptorProto{
+			{
+				Name:       proto.String("GetThing"),
+				InputType:  proto.String(".my.pkg.GetThingRequest"),
+				OutputType: proto.String(".my.pkg.Thing"),
+				Options: httpOpts(&annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Get{Get: "/v1/{name=projects/*/things/*}"},
+				}),
+			},
+			{
+				Name:       proto.String("CreateThing"),
+				InputType:  proto.String(".my.pkg.Thing"),
+				OutputType: proto.String(".my.pkg.Thing"),
+				Options: httpOpts(&annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Post{Post: "/v1/things/{size}"},
+					Body:    "*",
+				}),
+			},
+			{
+				Name:       proto.String("UpdateThing"),
+				InputType:  proto.String(".my.pkg.UpdateThingRequest"),
+				OutputType: proto.String(".my.pkg.Thing"),
+				Options: httpOpts(&annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Patch{Patch: "/v1/{thing.name=projects/*/things/*}"},
+					Body:    "thing",
+				}),
+			},
+			{
+				Name:       proto.String("PurgeThing"),
+				InputType:  proto.String(".my.pkg.Thing"),
+				OutputType: proto.String(emptyType),
+				Options: httpOpts(&annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Custom{Custom: &annotations.CustomHttpPattern{Kind: "PURGE", Path: "/v1/{name}:purge"}},
+				}),
+			},
+			{
+				Name:       proto.String("LongThing"),
+				InputType:  proto.String(".my.pkg.Thing"),
+				OutputType: proto.String(lroType),
+			},
+			{
+				Name:       proto.String("SecretThing"),
+				InputType:  proto.String(".my.pkg.Thing"),
+				OutputType: proto.String(".my.pkg.Thing"),
+			},
+			{
+				Name:            proto.String("ServerThings"),
+				InputType:       proto.String(".my.pkg.Thing"),
+				OutputType:      proto.String(".my.pkg.Thing"),
+				ServerStreaming: proto.Bool(true),
+			},
+			{
+				Name:            proto.String("BidiThings"),
+				InputType:       proto.String(".my.pkg.Thing"),
+				OutputType:      proto.String(".my.pkg.Thing"),
+				ClientStreaming: proto.Bool(true),
+				ServerStreaming: proto.Bool(true),
+			},
+		},
+		Options: &descriptor.ServiceOptions{},
+	}
+	if err := proto.SetExtension(serv.Options, annotations.E_DefaultHost, proto.String("foo.googleapis.com")); err != nil {
+		panic(err)
+	}
+	g.descInfo.ParentFile[serv] = file
+	return serv
+}
+
+func TestRESTClient(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	serv := restService(&g)
+
+	if err := g.genRESTClient(serv, "Foo"); err != nil {
+		t.Fatal(err)
+	}
+	diff(t, "rest_client", g.pt.String(), filepath.Join("testdata", "rest_client.want"))
+}
+
+func TestRESTHelpers(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+
+	g.genRESTHelpers()
+	diff(t, "rest_helpers", g.pt.String(), filepath.Join("testdata", "rest_helpers.want"))
+}
+
+func TestRESTClientError(t *testing.T) {
+	for _, rule := range []*annotations.HttpRule{
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{nonexistent}"}},
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{filter}"}},
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{ids}"}},
+		{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name.foo}"}},
+		{Pattern: &annotations.HttpRule_Post{Post: "/v1/things"}, Body: "name"},
+		{Pattern: &annotations.HttpRule_Post{Post: "/v1/things"}, Body: "nonexistent"},
+		{},
+	} {
+		var g generator
+		g.imports = map[pbinfo.ImportSpec]bool{}
+		serv := restService(&g)
+		m := serv.Method[0]
+		if err := proto.SetExtension(m.Options, annotations.E_Http, rule); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.restMethod("Foo", serv, m); err == nil {
+			t.Errorf("restMethod with rule %v: expected error", rule)
+		}
+	}
+}
+
+// TestRESTClientHTTP runs testdata/rest_http_test.go against the REST methods generated for restService,
+// sending requests to an httptest server. The methods are built in a temporary module, along with
+// the types generated by protoc-gen-go, so the go command downloads the dependencies of the generated code.
+func TestRESTClientHTTP(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	files := genTestPackage(t, "rest")
+	harness, err := ioutil.ReadFile(filepath.Join("testdata", "rest_http_test.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["client/rest_http_test.go"] = string(harness)
+	goTestPackage(t, files)
+}
+
+// testModule is the module of the package generated by genTestPackage.
+// The generated clients use an internal package of cloud.google.com/go, so the module must be in its tree.
+const testModule = "cloud.google.com/go/gapicgentest"
+
+// genTestPackage generates the package of the client of restService, as protoc would with
+// protoc-gen-go and protoc-gen-go_gapic, transports being the value of the transport option.
+// The files are keyed by their path in testModule: the messages and gRPC stubs are in the root,
+// the client in "client".
+func genTestPackage(t *testing.T, transports string) map[string]string {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	serv := restService(&g)
+	for _, m := range serv.GetMethod() {
+		if m.GetOutputType() != lroType {
+			continue
+		}
+		m.Options = &descriptor.MethodOptions{}
+		if err := proto.SetExtension(m.Options, annotations.E_LongrunningOperationTypes, &annotations.LongrunningOperationTypes{
+			Response: "Thing",
+			Metadata: "Thing",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The files of the types, as protoc would describe them to protoc-gen-go.
+	depFile := func(name, pkg, goPkg, msg string) *descriptor.FileDescriptorProto {
+		return &descriptor.FileDescriptorProto{
+			Name:        proto.String(name),
+			Package:     proto.String(pkg),
+			MessageType: []*descriptor.DescriptorProto{{Name: proto.String(msg)}},
+			Options:     &descriptor.FileOptions{GoPackage: proto.String(goPkg)},
+			Syntax:      proto.String("proto3"),
+		}
+	}
+	deps := []*descriptor.FileDescriptorProto{
+		depFile("google/protobuf/empty.proto", "google.protobuf", "github.com/golang/protobuf/ptypes/empty", "Empty"),
+		depFile("google/protobuf/field_mask.proto", "google.protobuf", "google.golang.org/genproto/protobuf/field_mask;field_mask", "FieldMask"),
+		depFile("google/longrunning/operations.proto", "google.longrunning", "google.golang.org/genproto/googleapis/longrunning;longrunning", "Operation"),
+	}
+	file := &descriptor.FileDescriptorProto{
+		Name:    proto.String("foo.proto"),
+		Package: proto.String("my.pkg"),
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name: proto.String("View"),
+			Value: []*descriptor.EnumValueDescriptorProto{
+				{Name: proto.String("BASIC"), Number: proto.Int32(0)},
+				{Name: proto.String("FULL"), Number: proto.Int32(1)},
+			},
+		}},
+		Service: []*descriptor.ServiceDescriptorProto{serv},
+		Options: &descriptor.FileOptions{GoPackage: proto.String(testModule + ";mypackage")},
+		Syntax:  proto.String("proto3"),
+	}
+	if err := proto.SetExtension(file.Options, annotations.E_Metadata, &annotations.Metadata{ProductName: "Foo"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, dep := range deps {
+		file.Dependency = append(file.Dependency, dep.GetName())
+	}
+	for _, name := range []string{"Thing", "Filter", "GetThingRequest", "UpdateThingRequest"} {
+		msg := g.descInfo.Type[".my.pkg."+name].(*descriptor.DescriptorProto)
+		for _, f := range msg.GetField() {
+			f.JsonName = proto.String(naming.JSONName(f.GetName()))
+		}
+		file.MessageType = append(file.MessageType, msg)
+	}
+	protoFiles := append(deps, file)
+
+	pg := protocgengo.New()
+	pg.Request = &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		Parameter:      proto.String("plugins=grpc,paths=source_relative"),
+		ProtoFile:      protoFiles,
+	}
+	pg.CommandLineParameters(pg.Request.GetParameter())
+	pg.WrapTypes()
+	pg.SetPackageNames()
+	pg.BuildTypeNameMap()
+	pg.GenerateAllFiles()
+
+	resp, err := Gen(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		Parameter:      proto.String("package-path=" + testModule + "/client,transport=" + transports),
+		ProtoFile:      protoFiles,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, f := range pg.Response.File {
+		files[f.GetName()] = f.GetContent()
+	}
+	// Files without a name continue the previous one.
+	var name string
+	for _, f := range resp.File {
+		if f.GetName() != "" {
+			name = strings.TrimPrefix(filepath.ToSlash(f.GetName()), testModule+"/")
+		}
+		files[name] += f.GetContent()
+	}
+	return files
+}
+
+// goTestPackage runs the tests of the "client" package of files, a package generated by genTestPackage.
+// The test is skipped if the dependencies of the generated code cannot be downloaded.
+func goTestPackage(t *testing.T, files map[string]string) {
+	files["go.mod"] = `module ` + testModule + `
+
+require (
+	cloud.google.com/go v0.34.0
+	github.com/golang/protobuf v1.2.0
+	github.com/googleapis/gax-go v1.0.0
+	golang.org/x/net v0.0.0-20180826012351-8a410e7b638d
+	google.golang.org/api v0.1.0
+	google.golang.org/genproto v0.0.0-20180914223249-4b56f30a1fd9
+	google.golang.org/grpc v1.15.0
+)
+`
+
+	dir, err := ioutil.TempDir("", "gapicgentest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range files {
+		fname := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	goCmd := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+		return cmd.CombinedOutput()
+	}
+	if out, err := goCmd("mod", "download"); err != nil {
+		t.Skipf("cannot download the dependencies of the generated code: %v\n%s", err, out)
+	}
+	if out, err := goCmd("test", "./client"); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
@@ -0,0 +1,481 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"github.com/googleapis/gapic-generator-go/internal/naming"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// signature describes a flattened method generated from a google.api.method_signature annotation.
+type signature struct {
+	// Name of the generated method.
+	name string
+
+	// Parameters of the method, in the order listed in the annotation.
+	params []sigParam
+
+	// The request message, with the fields to be set from params.
+	req *sigNode
+}
+
+type sigParam struct {
+	name, typ string
+	imports   []pbinfo.ImportSpec
+}
+
+// sigNode is a message-typed value in the request built by a flattened method.
+type sigNode struct {
+	msg *descriptor.DescriptorProto
+
+	// The fields to set, in the order they were first mentioned in the annotation.
+	// If the field is a message whose subfields are set, sub contains the subfields.
+	// Otherwise param is the name of the parameter the field is set to.
+	fields []*descriptor.FieldDescriptorProto
+	sub    []*sigNode
+	param  []string
+}
+
+// child returns the node for message-typed field f of n, creating it if necessary.
+func (n *sigNode) child(f *descriptor.FieldDescriptorProto, msg *descriptor.DescriptorProto) (*sigNode, error) {
+	for i, nf := range n.fields {
+		if nf != f {
+			continue
+		}
+		if n.sub[i] == nil {
+			return nil, errors.User(nil, "field %q is both a parameter and a parent of a parameter", f.GetName())
+		}
+		return n.sub[i], nil
+	}
+	c := &sigNode{msg: msg}
+	if err := n.add(f, c, ""); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (n *sigNode) set(f *descriptor.FieldDescriptorProto, param string) error {
+	return n.add(f, nil, param)
+}
+
+// add appends field f to the fields of n, set to sub or param.
+// At most one field of each oneof can be set.
+func (n *sigNode) add(f *descriptor.FieldDescriptorProto, sub *sigNode, param string) error {
+	for _, nf := range n.fields {
+		if nf == f {
+			return errors.User(nil, "field %q appears more than once", f.GetName())
+		}
+		if f.OneofIndex != nil && nf.OneofIndex != nil && nf.GetOneofIndex() == f.GetOneofIndex() {
+			oneof := n.msg.GetOneofDecl()[f.GetOneofIndex()]
+			return errors.User(nil, "fields %q and %q are both in oneof %q", nf.GetName(), f.GetName(), oneof.GetName())
+		}
+	}
+	n.fields = append(n.fields, f)
+	n.sub = append(n.sub, sub)
+	n.param = append(n.param, param)
+	return nil
+}
+
+// reservedParams are names used by flattened methods that parameters must not shadow.
+var reservedParams = map[string]bool{
+	"c":    true,
+	"ctx":  true,
+	"opts": true,
+	"req":  true,
+}
+
+// methodSignatures reads the google.api.method_signature annotation of m,
+// including the additional signatures, in the order they are declared.
+// Signatures without fields are skipped.
+func methodSignatures(m *descriptor.MethodDescriptorProto) ([]*annotations.MethodSignature, error) {
+	if m.GetOptions() == nil {
+		return nil, nil
+	}
+	eSig, err := proto.GetExtension(m.GetOptions(), annotations.E_MethodSignature)
+	if err == proto.ErrMissingExtension {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.E(err, "cannot read method_signature annotation")
+	}
+
+	var sigs []*annotations.MethodSignature
+	var add func(*annotations.MethodSignature)
+	add = func(s *annotations.MethodSignature) {
+		if len(s.GetFields()) > 0 {
+			sigs = append(sigs, s)
+		}
+		for _, as := range s.GetAdditionalSignatures() {
+			add(as)
+		}
+	}
+	add(eSig.(*annotations.MethodSignature))
+	return sigs, nil
+}
+
+// signaturePos returns the position of the method_signature annotation of m,
+// or of m if it is unknown.
+func (g *generator) signaturePos(m *descriptor.MethodDescriptorProto) errors.Pos {
+	return g.optionPos(m, m.GetOptions(), annotations.E_MethodSignature.Field)
+}
+
+// serviceSignatures determines the flattened methods of all methods in serv.
+// Method names are allocated in the order the methods and signatures are declared,
+// so that the result is the same for the client and the example files.
+// Signatures that cannot be flattened are skipped: the flattened methods of the others are returned,
+// along with the errors of the skipped ones.
+func (g *generator) serviceSignatures(serv *descriptor.ServiceDescriptorProto) (map[*descriptor.MethodDescriptorProto][]signature, error) {
+	taken, err := g.clientMembers(serv)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs errors.List
+	sigs := map[*descriptor.MethodDescriptorProto][]signature{}
+	for _, m := range serv.GetMethod() {
+		annos, err := methodSignatures(m)
+		if err != nil {
+			errs.Add(errors.At(g.pos(m), err, "method %s", m.GetName()))
+			continue
+		}
+		if len(annos) == 0 {
+			continue
+		}
+		if m.GetClientStreaming() {
+			errs.Add(errors.At(g.signaturePos(m), errors.User(nil, "method_signature is not supported on client-streaming methods"), "method %s", m.GetName()))
+			continue
+		}
+
+		for _, anno := range annos {
+			sig, err := g.signature(m, anno)
+			if err != nil {
+				errs.Add(errors.At(g.signaturePos(m), err, "method %s: signature %v", m.GetName(), anno.GetFields()))
+				continue
+			}
+
+			// If function_name is not given or collides with another method,
+			// derive a name from the fields instead.
+			name := anno.GetFunctionName()
+			if name == "" || taken[name] {
+				var sb strings.Builder
+				sb.WriteString(m.GetName())
+				sb.WriteString("With")
+				for i, f := range anno.GetFields() {
+					if i > 0 {
+						sb.WriteString("And")
+					}
+					sb.WriteString(naming.CamelCase(strings.Replace(f, ".", "_", -1)))
+				}
+				name = sb.String()
+			}
+			if taken[name] {
+				errs.Add(errors.At(g.signaturePos(m), errors.User(nil, "flattened method %s conflicts with another method, set a unique function_name", name), "method %s", m.GetName()))
+				continue
+			}
+			taken[name] = true
+			sig.name = name
+
+			sigs[m] = append(sigs[m], sig)
+		}
+	}
+	return sigs, errs.Err(0)
+}
+
+// signature resolves the fields of anno against the input type of m.
+func (g *generator) signature(m *descriptor.MethodDescriptorProto, anno *annotations.MethodSignature) (signature, error) {
+	inMsg, ok := g.descInfo.Type[m.GetInputType()].(*descriptor.DescriptorProto)
+	if !ok {
+		return signature{}, errors.E(nil, "cannot find message type %q, malformed descriptor?", m.GetInputType())
+	}
+
+	sig := signature{req: &sigNode{msg: inMsg}}
+
+	// Use the last element of each path as the parameter name,
+	// unless it is ambiguous; then use the entire path.
+	lastCount := map[string]int{}
+	for _, path := range anno.GetFields() {
+		lastCount[path[strings.LastIndexByte(path, '.')+1:]]++
+	}
+
+	for _, path := range anno.GetFields() {
+		elems := strings.Split(path, ".")
+		node := sig.req
+
+		for _, e := range elems[:len(elems)-1] {
+			f := findField(node.msg, e)
+			if f == nil {
+				return signature{}, errors.User(nil, "message %q has no field %q", node.msg.GetName(), e)
+			}
+			if f.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE || f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+				return signature{}, errors.User(nil, "field %q in path %q must be a non-repeated message", e, path)
+			}
+			msg, ok := g.descInfo.Type[f.GetTypeName()].(*descriptor.DescriptorProto)
+			if !ok {
+				return signature{}, errors.E(nil, "cannot find message type %q, malformed descriptor?", f.GetTypeName())
+			}
+			c, err := node.child(f, msg)
+			if err != nil {
+				return signature{}, err
+			}
+			node = c
+		}
+
+		last := elems[len(elems)-1]
+		f := findField(node.msg, last)
+		if f == nil {
+			return signature{}, errors.User(nil, "message %q has no field %q", node.msg.GetName(), last)
+		}
+
+		name := last
+		if lastCount[last] > 1 {
+			name = strings.Replace(path, ".", "_", -1)
+		}
+		name = lowerFirst(naming.CamelCase(name))
+		if token.Lookup(name).IsKeyword() || reservedParams[name] {
+			name += "Arg"
+		}
+		for _, p := range sig.params {
+			if p.name == name {
+				return signature{}, errors.User(nil, "duplicate parameter name %q", name)
+			}
+		}
+
+		typ, imports, err := g.fieldGoType(f)
+		if err != nil {
+			return signature{}, err
+		}
+		if err := node.set(f, name); err != nil {
+			return signature{}, err
+		}
+		sig.params = append(sig.params, sigParam{name: name, typ: typ, imports: imports})
+	}
+	return sig, nil
+}
+
+func findField(msg *descriptor.DescriptorProto, name string) *descriptor.FieldDescriptorProto {
+	for _, f := range msg.GetField() {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// goTypeName reports the name of the Go type generated by protoc-gen-go for t,
+// qualified by the import containing it.
+func (g *generator) goTypeName(t pbinfo.ProtoType) (string, pbinfo.ImportSpec, error) {
+	// Nested types are named Parent_Child.
+	parts := []string{t.GetName()}
+	top := t
+	for p := g.descInfo.ParentElement[t]; p != nil; p = g.descInfo.ParentElement[p] {
+		parts = append([]string{p.GetName()}, parts...)
+		top = p
+	}
+
+	imp, err := g.importSpec(top)
+	if err != nil {
+		return "", pbinfo.ImportSpec{}, err
+	}
+	return imp.Name + "." + strings.Join(parts, "_"), imp, nil
+}
+
+// fieldGoType reports the Go type of field f in the message generated by protoc-gen-go,
+// and the imports the type needs.
+// For the fields isPointerField reports, it is the type pointed to by the field.
+func (g *generator) fieldGoType(f *descriptor.FieldDescriptorProto) (string, []pbinfo.ImportSpec, error) {
+	var elem string
+	var imports []pbinfo.ImportSpec
+
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_ENUM:
+		t := g.descInfo.Type[f.GetTypeName()]
+		if t == nil {
+			return "", nil, errors.E(nil, "cannot find type %q, malformed descriptor?", f.GetTypeName())
+		}
+
+		if msg := mapEntry(t); msg != nil {
+			key, kImps, err := g.fieldGoType(findField(msg, "key"))
+			if err != nil {
+				return "", nil, err
+			}
+			val, vImps, err := g.fieldGoType(findField(msg, "value"))
+			if err != nil {
+				return "", nil, err
+			}
+			return fmt.Sprintf("map[%s]%s", key, val), append(kImps, vImps...), nil
+		}
+
+		name, imp, err := g.goTypeName(t)
+		if err != nil {
+			return "", nil, err
+		}
+		elem = name
+		if f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			elem = "*" + name
+		}
+		imports = append(imports, imp)
+
+	case descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return "", nil, errors.At(g.pos(f), errors.User(nil, "groups are not supported"), "field %s", f.GetName())
+
+	default:
+		elem = pbinfo.GoTypeForPrim[f.GetType()]
+		if elem == "" {
+			return "", nil, errors.E(nil, "field %q: unrecognized type %v", f.GetName(), f.GetType())
+		}
+	}
+
+	if f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		elem = "[]" + elem
+	}
+	return elem, imports, nil
+}
+
+// isPointerField reports whether protoc-gen-go generates field f of msg as a pointer to a scalar,
+// as it does for the optional and required scalar fields of proto2 messages, except bytes.
+func (g *generator) isPointerField(msg *descriptor.DescriptorProto, f *descriptor.FieldDescriptorProto) bool {
+	switch {
+	case f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED, f.OneofIndex != nil:
+		return false
+	case f.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		f.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP,
+		f.GetType() == descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return false
+	}
+
+	// Nested messages are described by the file of their outermost message.
+	var top pbinfo.ProtoType = msg
+	for p := g.descInfo.ParentElement[top]; p != nil; p = g.descInfo.ParentElement[p] {
+		top = p
+	}
+	// protoc leaves the syntax of proto2 files unset.
+	return g.descInfo.ParentFile[top].GetSyntax() != "proto3"
+}
+
+// returnType reports the return type of the client method generated for m.
+// It must agree with the dispatch in genMethod.
+func (g *generator) returnType(serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) (string, error) {
+	if m.GetOutputType() == lroType {
+		return fmt.Sprintf("(*%s, error)", g.lroTypeName(m)), nil
+	}
+	if m.GetOutputType() == emptyType {
+		return "error", nil
+	}
+
+	if pi, err := g.pagingInfoOf(serv, m); err != nil {
+		return "", err
+	} else if pi != nil {
+		iter, err := g.iterTypeOf(pi.elemField)
+		if err != nil {
+			return "", err
+		}
+		return "*" + iter.iterTypeName, nil
+	}
+
+	if m.GetServerStreaming() {
+		servSpec, err := g.importSpec(serv)
+		if err != nil {
+			return "", err
+		}
+		g.imports[servSpec] = true
+		return fmt.Sprintf("(%s.%s_%sClient, error)", servSpec.Name, serv.GetName(), m.GetName()), nil
+	}
+
+	outType := g.descInfo.Type[m.GetOutputType()]
+	outSpec, err := g.importSpec(outType)
+	if err != nil {
+		return "", err
+	}
+	g.imports[outSpec] = true
+	return fmt.Sprintf("(*%s.%s, error)", outSpec.Name, outType.GetName()), nil
+}
+
+// flattenedCall generates the flattened method sig of m.
+// The method builds the request from its parameters and calls the method generated for m.
+func (g *generator) flattenedCall(servName string, serv *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto, sig signature) error {
+	ret, err := g.returnType(serv, m)
+	if err != nil {
+		return err
+	}
+
+	var params strings.Builder
+	for _, prm := range sig.params {
+		fmt.Fprintf(&params, "%s %s, ", prm.name, prm.typ)
+		for _, imp := range prm.imports {
+			g.imports[imp] = true
+		}
+	}
+
+	p := g.printf
+
+	p("// %s calls %s with a request built from the given fields.", sig.name, g.clientMethodName(m))
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, %sopts ...gax.CallOption) %s",
+		sig.name, params.String(), ret))
+	if err := g.printSigNode(sig.req, "req := ", ""); err != nil {
+		return err
+	}
+	p("return c.%s(ctx, req, opts...)", g.clientMethodName(m))
+	p("}")
+	p("")
+	return nil
+}
+
+// printSigNode prints the composite literal of the message described by n.
+// The literal is preceded by prefix and followed by suffix.
+func (g *generator) printSigNode(n *sigNode, prefix, suffix string) error {
+	p := g.printf
+
+	typ, imp, err := g.goTypeName(n.msg)
+	if err != nil {
+		return err
+	}
+	g.imports[imp] = true
+
+	p("%s&%s{", prefix, typ)
+	for i, f := range n.fields {
+		fieldName := naming.CamelCase(f.GetName())
+
+		// Fields in a oneof are set through a wrapper type.
+		inOneof := f.OneofIndex != nil
+		if inOneof {
+			oneof := n.msg.GetOneofDecl()[f.GetOneofIndex()]
+			p("%s: &%s_%s{", naming.CamelCase(oneof.GetName()), typ, fieldName)
+		}
+
+		if sub := n.sub[i]; sub != nil {
+			if err := g.printSigNode(sub, fieldName+": ", ","); err != nil {
+				return err
+			}
+		} else if g.isPointerField(n.msg, f) {
+			p("%s: &%s,", fieldName, n.param[i])
+		} else {
+			p("%s: %s,", fieldName, n.param[i])
+		}
+
+		if inOneof {
+			p("},")
+		}
+	}
+	p("}%s", suffix)
+	return nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// signatureService returns a service whose method CreateThing has several signatures.
+// The types are registered in g.
+func signatureService(g *generator) *descriptor.ServiceDescriptorProto {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+	labelp := func(l descriptor.FieldDescriptorProto_Label) *descriptor.FieldDescriptorProto_Label {
+		return &l
+	}
+	optional := labelp(descriptor.FieldDescriptorProto_LABEL_OPTIONAL)
+
+	labelsEntry := &descriptor.DescriptorProto{
+		Name: proto.String("LabelsEntry"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("key"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{Name: proto.String("value"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+		},
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	thing := &descriptor.DescriptorProto{
+		Name: proto.String("Thing"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("name"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{
+				Name:     proto.String("labels"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String(".my.pkg.Thing.LabelsEntry"),
+				Label:    labelp(descriptor.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		},
+		NestedType: []*descriptor.DescriptorProto{labelsEntry},
+	}
+	req := &descriptor.DescriptorProto{
+		Name: proto.String("CreateThingRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{Name: proto.String("parent"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional},
+			{
+				Name:     proto.String("thing"),
+				Type:     typep(descriptor.FieldDescriptorProto_TYPE_MESSAGE),
+				TypeName: proto.String(".my.pkg.Thing"),
+				Label:    optional,
+			},
+			{Name: proto.String("type"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT64), Label: optional},
+			{Name: proto.String("name"), Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING), Label: optional, OneofIndex: proto.Int32(0)},
+			{Name: proto.String("id"), Type: typep(descriptor.FieldDescriptorProto_TYPE_INT64), Label: optional, OneofIndex: proto.Int32(0)},
+		},
+		OneofDecl: []*descriptor.OneofDescriptorProto{
+			{Name: proto.String("choice")},
+		},
+	}
+
+	file := &descriptor.FileDescriptorProto{
+		Package: proto.String("my.pkg"),
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("mypackage"),
+		},
+		Syntax: proto.String("proto3"),
+	}
+
+	commonTypes(g)
+	g.descInfo.ParentElement = map[pbinfo.ProtoType]pbinfo.ProtoType{
+		labelsEntry: thing,
+	}
+	g.descInfo.Type[".my.pkg.Thing"] = thing
+	g.descInfo.Type[".my.pkg.Thing.LabelsEntry"] = labelsEntry
+	g.descInfo.Type[".my.pkg.CreateThingRequest"] = req
+	g.descInfo.ParentFile[thing] = file
+	g.descInfo.ParentFile[req] = file
+
+	sig := &annotations.MethodSignature{
+		// Conflicts with the method itself; a name is derived instead.
+		FunctionName: "CreateThing",
+		Fields:       []string{"parent", "thing.name", "type"},
+		AdditionalSignatures: []*annotations.MethodSignature{
+			{
+				FunctionName: "CreateThingFromThing",
+				Fields:       []string{"parent", "thing"},
+			},
+			{
+				Fields: []string{"thing.labels", "name"},
+			},
+		},
+	}
+	opts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(opts, annotations.E_MethodSignature, sig); err != nil {
+		panic(err)
+	}
+
+	serv := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("Foo"),
+		Method: []*descriptor.MethodDescriptorProto{
+			{
+				Name:       proto.String("CreateThing"),
+				InputType:  proto.String(".my.pkg.CreateThingRequest"),
+				OutputType: proto.String(".my.pkg.Thing"),
+				Options:    opts,
+			},
+		},
+	}
+	g.descInfo.ParentFile[serv] = file
+	return serv
+}
+
+func TestFlattenedCall(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	serv := signatureService(&g)
+
+	sigs, err := g.serviceSignatures(serv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := serv.Method[0]
+
+	var names []string
+	for _, sig := range sigs[m] {
+		names = append(names, sig.name)
+		if err := g.flattenedCall("Foo", serv, m, sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantNames := []string{
+		"CreateThingWithParentAndThingNameAndType",
+		"CreateThingFromThing",
+		"CreateThingWithThingLabelsAndName",
+	}
+	if len(names) != len(wantNames) {
+		t.Fatalf("got flattened methods %q, want %q", names, wantNames)
+	}
+	for i := range names {
+		if names[i] != wantNames[i] {
+			t.Errorf("got flattened methods %q, want %q", names, wantNames)
+			break
+		}
+	}
+	diff(t, "flattened", g.pt.String(), filepath.Join("testdata", "flattened.want"))
+}
+
+func TestServiceSignaturesSkip(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	serv := signatureService(&g)
+
+	// A signature that cannot be flattened, along with the good ones.
+	m := serv.Method[0]
+	eSig, err := proto.GetExtension(m.Options, annotations.E_MethodSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := eSig.(*annotations.MethodSignature)
+	sig.AdditionalSignatures = append(sig.AdditionalSignatures, &annotations.MethodSignature{Fields: []string{"nonexistent"}})
+	if err := proto.SetExtension(m.Options, annotations.E_MethodSignature, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	// A method whose signatures are not supported.
+	uploadOpts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(uploadOpts, annotations.E_MethodSignature, &annotations.MethodSignature{Fields: []string{"parent"}}); err != nil {
+		t.Fatal(err)
+	}
+	upload := &descriptor.MethodDescriptorProto{
+		Name:            proto.String("UploadThings"),
+		InputType:       proto.String(".my.pkg.CreateThingRequest"),
+		OutputType:      proto.String(".my.pkg.Thing"),
+		ClientStreaming: proto.Bool(true),
+		Options:         uploadOpts,
+	}
+	serv.Method = append(serv.Method, upload)
+
+	sigs, err := g.serviceSignatures(serv)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, s := range []string{"method CreateThing: signature [nonexistent]", "method UploadThings"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q does not contain %q", err, s)
+		}
+	}
+	if n := len(sigs[m]); n != 3 {
+		t.Errorf("got %d flattened methods of CreateThing, want the 3 good ones", n)
+	}
+	if n := len(sigs[upload]); n != 0 {
+		t.Errorf("got %d flattened methods of UploadThings, want none", n)
+	}
+}
+
+func TestFlattenedExample(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	serv := signatureService(&g)
+
+	if err := g.genExampleFile(serv, "Foo"); err != nil {
+		t.Fatal(err)
+	}
+	diff(t, "flattened_example", g.pt.String(), filepath.Join("testdata", "flattened_example.want"))
+}
+
+func TestFlattenedCallProto2(t *testing.T) {
+	var g generator
+	g.imports = map[pbinfo.ImportSpec]bool{}
+	serv := signatureService(&g)
+	g.descInfo.ParentFile[serv].Syntax = nil
+
+	sigs, err := g.serviceSignatures(serv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := serv.Method[0]
+	for _, sig := range sigs[m] {
+		if err := g.flattenedCall("Foo", serv, m, sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Scalar fields are pointers, except in oneofs; the parameters are not.
+	got := g.pt.String()
+	for _, want := range []string{
+		"parent string, name string, typeArg int64,",
+		"Parent: &parent,",
+		"Name: &name,",
+		"Type: &typeArg,",
+		"Thing: thing,",
+		"Labels: labels,",
+		"CreateThingRequest_Name{\n\t\t\tName: name,",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("cannot find %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestSignatureError(t *testing.T) {
+	for _, fields := range [][]string{
+		{"nonexistent"},
+		{"parent.name"},
+		{"thing", "thing.name"},
+		{"thing.name", "thing"},
+		{"parent", "parent"},
+		// Only one field of a oneof can be set.
+		{"name", "id"},
+	} {
+		var g generator
+		g.imports = map[pbinfo.ImportSpec]bool{}
+		serv := signatureService(&g)
+		m := serv.Method[0]
+
+		if _, err := g.signature(m, &annotations.MethodSignature{Fields: fields}); err == nil {
+			t.Errorf("signature(%q): expected error", fields)
+		}
+	}
+}
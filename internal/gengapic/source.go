@@ -0,0 +1,200 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+)
+
+// sourceSpan is the location of an element in its proto file.
+// Lines and columns start at 1, and the end is exclusive.
+type sourceSpan struct {
+	file                                 string
+	startLine, startCol, endLine, endCol int
+}
+
+// optionKey identifies an option set in an options message,
+// such as the google.api.http option of a method.
+type optionKey struct {
+	opts  proto.Message
+	field int32
+}
+
+// Field numbers of the descriptor messages, used in the paths of SourceCodeInfo locations.
+const (
+	fileMessageTag   = 4
+	fileEnumTag      = 5
+	fileServiceTag   = 6
+	fileOptionsTag   = 8
+	messageFieldTag  = 2
+	messageNestedTag = 3
+	messageEnumTag   = 4
+	messageOptsTag   = 7
+	fieldOptionsTag  = 8
+	enumValueTag     = 2
+	enumOptionsTag   = 3
+	valueOptionsTag  = 3
+	serviceMethodTag = 2
+	serviceOptsTag   = 3
+	methodOptionsTag = 4
+)
+
+// indexSpans records the spans of the elements of f found in its SourceCodeInfo:
+// services, methods, messages, fields, enums, enum values, their options messages
+// and the options set in them.
+func (g *generator) indexSpans(f *descriptor.FileDescriptorProto) {
+	for _, loc := range f.GetSourceCodeInfo().GetLocation() {
+		span, ok := spanOf(f.GetName(), loc.GetSpan())
+		if !ok {
+			continue
+		}
+		elem, rest := resolvePath(f, loc.GetPath())
+		switch {
+		case elem == proto.Message(f):
+			// The file itself is not indexed.
+		case len(rest) == 0:
+			g.spans[elem] = span
+		case len(rest) == 1 && isOptions(elem):
+			// rest is the field number of an option set in elem.
+			// Locations of parts of the option's value are not indexed.
+			g.optionSpans[optionKey{elem, rest[0]}] = span
+		}
+	}
+}
+
+// spanOf converts the span of a SourceCodeInfo location, made of the zero-based
+// start line, start column, end line if different from the start line, and end column.
+func spanOf(file string, s []int32) (sourceSpan, bool) {
+	span := sourceSpan{file: file}
+	switch len(s) {
+	case 3:
+		span.startLine, span.startCol, span.endLine, span.endCol = int(s[0]), int(s[1]), int(s[0]), int(s[2])
+	case 4:
+		span.startLine, span.startCol, span.endLine, span.endCol = int(s[0]), int(s[1]), int(s[2]), int(s[3])
+	default:
+		return sourceSpan{}, false
+	}
+	span.startLine++
+	span.startCol++
+	span.endLine++
+	span.endCol++
+	return span, true
+}
+
+// resolvePath returns the element designated by the longest prefix of path starting from elem,
+// and the rest of path.
+//
+// A path alternates field numbers and indexes into repeated fields:
+// from a file, [6, 1, 2, 0] is the first method of the second service.
+// Options messages are designated by the field number of the options field alone,
+// as in [6, 1, 3], the options of the second service.
+func resolvePath(elem proto.Message, path []int32) (proto.Message, []int32) {
+	// at reports whether path starts with tag.
+	at := func(tag int32) bool {
+		return len(path) >= 1 && path[0] == tag
+	}
+	// in reports whether path starts with tag and an index into a slice of length n.
+	in := func(tag int32, n int) bool {
+		return len(path) >= 2 && path[0] == tag && int(path[1]) < n
+	}
+
+	var next proto.Message
+	switch e := elem.(type) {
+	case *descriptor.FileDescriptorProto:
+		switch {
+		case at(fileOptionsTag) && e.Options != nil:
+			return e.Options, path[1:]
+		case in(fileMessageTag, len(e.MessageType)):
+			next = e.MessageType[path[1]]
+		case in(fileEnumTag, len(e.EnumType)):
+			next = e.EnumType[path[1]]
+		case in(fileServiceTag, len(e.Service)):
+			next = e.Service[path[1]]
+		}
+	case *descriptor.DescriptorProto:
+		switch {
+		case at(messageOptsTag) && e.Options != nil:
+			return e.Options, path[1:]
+		case in(messageFieldTag, len(e.Field)):
+			next = e.Field[path[1]]
+		case in(messageNestedTag, len(e.NestedType)):
+			next = e.NestedType[path[1]]
+		case in(messageEnumTag, len(e.EnumType)):
+			next = e.EnumType[path[1]]
+		}
+	case *descriptor.FieldDescriptorProto:
+		if at(fieldOptionsTag) && e.Options != nil {
+			return e.Options, path[1:]
+		}
+	case *descriptor.EnumDescriptorProto:
+		switch {
+		case at(enumOptionsTag) && e.Options != nil:
+			return e.Options, path[1:]
+		case in(enumValueTag, len(e.Value)):
+			next = e.Value[path[1]]
+		}
+	case *descriptor.EnumValueDescriptorProto:
+		if at(valueOptionsTag) && e.Options != nil {
+			return e.Options, path[1:]
+		}
+	case *descriptor.ServiceDescriptorProto:
+		switch {
+		case at(serviceOptsTag) && e.Options != nil:
+			return e.Options, path[1:]
+		case in(serviceMethodTag, len(e.Method)):
+			next = e.Method[path[1]]
+		}
+	case *descriptor.MethodDescriptorProto:
+		if at(methodOptionsTag) && e.Options != nil {
+			return e.Options, path[1:]
+		}
+	}
+
+	if next == nil {
+		return elem, path
+	}
+	return resolvePath(next, path[2:])
+}
+
+// isOptions reports whether m is the options message of a descriptor.
+func isOptions(m proto.Message) bool {
+	switch m.(type) {
+	case *descriptor.FileOptions, *descriptor.MessageOptions, *descriptor.FieldOptions,
+		*descriptor.EnumOptions, *descriptor.EnumValueOptions, *descriptor.ServiceOptions, *descriptor.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// pos returns the position of element e in its proto file,
+// or the zero errors.Pos if the source of e is unknown.
+func (g *generator) pos(e proto.Message) errors.Pos {
+	span, ok := g.spans[e]
+	if !ok {
+		return errors.Pos{}
+	}
+	return errors.Pos{File: span.file, Line: span.startLine, Col: span.startCol}
+}
+
+// optionPos returns the position of the option with the given field number in options message opts,
+// or of owner, the element opts belongs to, if the option's position is unknown.
+func (g *generator) optionPos(owner, opts proto.Message, field int32) errors.Pos {
+	if span, ok := g.optionSpans[optionKey{opts, field}]; ok {
+		return errors.Pos{File: span.file, Line: span.startLine, Col: span.startCol}
+	}
+	return g.pos(owner)
+}
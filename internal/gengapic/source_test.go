@@ -0,0 +1,102 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gengapic
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/errors"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestIndexSpans(t *testing.T) {
+	typep := func(t descriptor.FieldDescriptorProto_Type) *descriptor.FieldDescriptorProto_Type {
+		return &t
+	}
+
+	field := &descriptor.FieldDescriptorProto{
+		Name: proto.String("page_size"),
+		Type: typep(descriptor.FieldDescriptorProto_TYPE_STRING),
+	}
+	msg := &descriptor.DescriptorProto{
+		Name:  proto.String("ListRequest"),
+		Field: []*descriptor.FieldDescriptorProto{field},
+	}
+	meth := &descriptor.MethodDescriptorProto{
+		Name:    proto.String("GetThing"),
+		Options: &descriptor.MethodOptions{},
+	}
+	other := &descriptor.MethodDescriptorProto{
+		Name: proto.String("DeleteThing"),
+	}
+	serv := &descriptor.ServiceDescriptorProto{
+		Name:   proto.String("Foo"),
+		Method: []*descriptor.MethodDescriptorProto{meth, other},
+	}
+	f := &descriptor.FileDescriptorProto{
+		Name:        proto.String("foo.proto"),
+		MessageType: []*descriptor.DescriptorProto{msg},
+		Service:     []*descriptor.ServiceDescriptorProto{serv},
+	}
+
+	sigField := annotations.E_MethodSignature.Field
+	f.SourceCodeInfo = &descriptor.SourceCodeInfo{
+		Location: []*descriptor.SourceCodeInfo_Location{
+			{Path: []int32{}, Span: []int32{0, 0, 60, 0}},
+			{Path: []int32{4, 0}, Span: []int32{9, 0, 11, 1}},
+			{Path: []int32{4, 0, 2, 0}, Span: []int32{10, 2, 23}},
+			{Path: []int32{6, 0}, Span: []int32{39, 0, 50, 1}, LeadingComments: proto.String("")},
+			{Path: []int32{6, 0, 2, 0}, Span: []int32{41, 2, 44, 3}, LeadingComments: proto.String("")},
+			{Path: []int32{6, 0, 2, 0, 4}, Span: []int32{42, 4, 43, 5}},
+			{Path: []int32{6, 0, 2, 0, 4, sigField}, Span: []int32{42, 4, 45}},
+			// Parts of the option's value and invalid paths are ignored.
+			{Path: []int32{6, 0, 2, 0, 4, sigField, 1}, Span: []int32{42, 30, 45}},
+			{Path: []int32{4, 5}, Span: []int32{55, 0, 56, 1}},
+			{Path: []int32{6, 0, 2, 1}, Span: []int32{1}, LeadingComments: proto.String("")},
+		},
+	}
+
+	var g generator
+	g.init([]*descriptor.FileDescriptorProto{f})
+
+	for _, tst := range []struct {
+		name string
+		got  errors.Pos
+		want errors.Pos
+	}{
+		{"message", g.pos(msg), errors.Pos{File: "foo.proto", Line: 10, Col: 1}},
+		{"field", g.pos(field), errors.Pos{File: "foo.proto", Line: 11, Col: 3}},
+		{"service", g.pos(serv), errors.Pos{File: "foo.proto", Line: 40, Col: 1}},
+		{"method", g.pos(meth), errors.Pos{File: "foo.proto", Line: 42, Col: 3}},
+		{"options", g.pos(meth.Options), errors.Pos{File: "foo.proto", Line: 43, Col: 5}},
+		{"option", g.signaturePos(meth), errors.Pos{File: "foo.proto", Line: 43, Col: 5}},
+		{"unknown option", g.optionPos(meth, meth.Options, 1049), errors.Pos{File: "foo.proto", Line: 42, Col: 3}},
+		{"invalid span", g.pos(other), errors.Pos{}},
+	} {
+		if tst.got != tst.want {
+			t.Errorf("%s: got %v, want %v", tst.name, tst.got, tst.want)
+		}
+	}
+	if got := len(g.optionSpans); got != 1 {
+		t.Errorf("got %d option spans, want 1", got)
+	}
+
+	_, err := g.configuredPaging(msg, msg, &pagingConfig{pageSizeField: "page_size"})
+	if got, want := err.Error(), "foo.proto:11:3: page size field page_size of ListRequest: must be a 32 or 64-bit integer"; got != want {
+		t.Errorf("configuredPaging: got %q, want %q", got, want)
+	}
+}
@@ -14,27 +14,34 @@
 
 package gengapic
 
-import "github.com/golang/protobuf/protoc-gen-go/descriptor"
+import (
+	"fmt"
 
-// Used for both bidi and client streaming.
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gapic-generator-go/internal/pbinfo"
+)
+
+// noRequestStreamCall generates the method for bidi streaming method m.
 func (g *generator) noRequestStreamCall(servName string, s *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
 	p := g.printf
 
-	servSpec, err := g.descInfo.ImportSpec(s)
+	servSpec, err := g.importSpec(s)
 	if err != nil {
 		return err
 	}
 	g.imports[servSpec] = true
 
-	p("func (c *%sClient) %s(ctx context.Context, opts ...gax.CallOption) (%s.%s_%sClient, error) {",
-		servName, m.GetName(), servSpec.Name, s.GetName(), m.GetName())
-	g.insertMetadata()
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, opts ...gax.CallOption) (%s.%s_%sClient, error)",
+		g.clientMethodName(m), servSpec.Name, s.GetName(), m.GetName()))
+	if err := g.insertMetadata(m); err != nil {
+		return err
+	}
 	g.appendCallOpts(m)
 	p("  var resp %s.%s_%sClient", servSpec.Name, s.GetName(), m.GetName())
 
 	p("  err := gax.Invoke(ctx, func(ctx context.Context, settings gax.CallSettings) error {")
 	p("    var err error")
-	p("    resp, err = c.%s.%s(ctx, settings.GRPC...)", grpcClientField(servName), m.GetName())
+	p("    resp, err = c.%s.%s(ctx, settings.GRPC...)", g.grpcClientField(servName), m.GetName())
 	p("    return err")
 	p("  }, opts...)")
 	p("  if err != nil {")
@@ -49,13 +56,13 @@ func (g *generator) noRequestStreamCall(servName string, s *descriptor.ServiceDe
 func (g *generator) serverStreamCall(servName string, s *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
 	inType := g.descInfo.Type[*m.InputType]
 
-	inSpec, err := g.descInfo.ImportSpec(inType)
+	inSpec, err := g.importSpec(inType)
 	if err != nil {
 		return err
 	}
 	g.imports[inSpec] = true
 
-	servSpec, err := g.descInfo.ImportSpec(s)
+	servSpec, err := g.importSpec(s)
 	if err != nil {
 		return err
 	}
@@ -63,15 +70,17 @@ func (g *generator) serverStreamCall(servName string, s *descriptor.ServiceDescr
 
 	p := g.printf
 
-	p("func (c *%sClient) %s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) (%s.%s_%sClient, error) {",
-		servName, m.GetName(), inSpec.Name, inType.GetName(), servSpec.Name, s.GetName(), m.GetName())
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, req *%s.%s, opts ...gax.CallOption) (%s.%s_%sClient, error)",
+		g.clientMethodName(m), inSpec.Name, inType.GetName(), servSpec.Name, s.GetName(), m.GetName()))
 
-	g.insertMetadata()
+	if err := g.insertMetadata(m); err != nil {
+		return err
+	}
 	g.appendCallOpts(m)
 	p("  var resp %s.%s_%sClient", servSpec.Name, s.GetName(), m.GetName())
 	p("err := gax.Invoke(ctx, func(ctx context.Context, settings gax.CallSettings) error {")
 	p("  var err error")
-	p("  resp, err = %s", grpcClientCall(servName, m.GetName()))
+	p("  resp, err = %s", g.grpcClientCall(servName, m.GetName()))
 	p("  return err")
 	p("}, opts...)")
 	p("if err != nil {")
@@ -84,3 +93,92 @@ func (g *generator) serverStreamCall(servName string, s *descriptor.ServiceDescr
 
 	return nil
 }
+
+// clientStreamCall generates the method for client streaming method m,
+// returning a stream of the type named by clientStreamTypeName.
+// The stream is opened with the call options of m; retries only apply to opening the stream.
+func (g *generator) clientStreamCall(servName string, s *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
+	p := g.printf
+
+	servSpec, err := g.importSpec(s)
+	if err != nil {
+		return err
+	}
+	g.imports[servSpec] = true
+
+	streamType := g.clientStreamTypeName(m)
+	g.clientMethod(servName, fmt.Sprintf("%s(ctx context.Context, opts ...gax.CallOption) (*%s, error)", g.clientMethodName(m), streamType))
+	if err := g.insertMetadata(m); err != nil {
+		return err
+	}
+	g.appendCallOpts(m)
+	p("  var stream %s.%s_%sClient", servSpec.Name, s.GetName(), m.GetName())
+	p("  err := gax.Invoke(ctx, func(ctx context.Context, settings gax.CallSettings) error {")
+	p("    var err error")
+	p("    stream, err = c.%s.%s(ctx, settings.GRPC...)", g.grpcClientField(servName), m.GetName())
+	p("    return err")
+	p("  }, opts...)")
+	p("  if err != nil {")
+	p("    return nil, err")
+	p("  }")
+	p("  return &%s{stream: stream}, nil", streamType)
+	p("}")
+	p("")
+	return nil
+}
+
+// clientStreamType generates the type wrapping the stream of client streaming method m.
+func (g *generator) clientStreamType(s *descriptor.ServiceDescriptorProto, m *descriptor.MethodDescriptorProto) error {
+	p := g.printf
+
+	servSpec, err := g.importSpec(s)
+	if err != nil {
+		return err
+	}
+	g.imports[servSpec] = true
+
+	inType := g.descInfo.Type[m.GetInputType()]
+	inSpec, err := g.importSpec(inType)
+	if err != nil {
+		return err
+	}
+	g.imports[inSpec] = true
+
+	outType := g.descInfo.Type[m.GetOutputType()]
+	outSpec, err := g.importSpec(outType)
+	if err != nil {
+		return err
+	}
+	g.imports[outSpec] = true
+
+	streamType := g.clientStreamTypeName(m)
+
+	p("// %s is the stream of requests sent by %s.", streamType, g.clientMethodName(m))
+	p("type %s struct {", streamType)
+	p("  stream %s.%s_%sClient", servSpec.Name, s.GetName(), m.GetName())
+	p("}")
+	p("")
+
+	p("// Send sends a request to the server.")
+	p("// It returns io.EOF if the stream was ended by the server;")
+	p("// the error of the call is then returned by CloseAndRecv.")
+	p("func (s *%s) Send(req *%s.%s) error {", streamType, inSpec.Name, inType.GetName())
+	p("  return s.stream.Send(req)")
+	p("}")
+	p("")
+
+	p("// CloseAndRecv closes the stream and waits for the response of the server.")
+	p("func (s *%s) CloseAndRecv() (*%s.%s, error) {", streamType, outSpec.Name, outType.GetName())
+	p("  return s.stream.CloseAndRecv()")
+	p("}")
+	p("")
+
+	p("// Context returns the context of the stream.")
+	p("func (s *%s) Context() context.Context {", streamType)
+	p("  return s.stream.Context()")
+	p("}")
+	p("")
+
+	g.imports[pbinfo.ImportSpec{Path: "golang.org/x/net/context"}] = true
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file is run by TestRESTClientHTTP, in the package of the client generated for restService
+// with the REST transport.
+
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	mypackagepb "cloud.google.com/go/gapicgentest"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRESTClient(t *testing.T) {
+	// The request received by the server, and the response it sends.
+	var (
+		gotMethod, gotPath, gotBody, gotType, gotParams, gotClient string
+		gotQuery                                                   url.Values
+
+		respStatus int
+		respBody   string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		gotMethod, gotPath, gotQuery, gotBody = r.Method, r.URL.EscapedPath(), r.URL.Query(), string(b)
+		gotType, gotParams, gotClient = r.Header.Get("Content-Type"), r.Header.Get("x-goog-request-params"), r.Header.Get("x-goog-api-client")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(respStatus)
+		w.Write([]byte(respBody))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, err := NewFooRESTClient(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tst := range []struct {
+		name string
+		call func() (proto.Message, error)
+
+		status int
+		resp   string
+
+		method, path, body string
+		query              url.Values
+		params             string
+		want               proto.Message
+		code               codes.Code
+	}{
+		{
+			name: "path and query",
+			call: func() (proto.Message, error) {
+				return c.GetThing(ctx, &mypackagepb.GetThingRequest{
+					Name:     "projects/p/things/a b",
+					View:     mypackagepb.View_FULL,
+					PageSize: 10,
+					Ids:      []int64{1, 2},
+					Tags:     []string{"x"},
+					Filter:   &mypackagepb.Filter{Query: "q", Raw: []byte{0xff}, Exact: true},
+					Things:   []*mypackagepb.Thing{{Name: "ignored"}},
+				})
+			},
+			status: http.StatusOK,
+			resp:   `{"name": "projects/p/things/a b", "size": "3", "unknown": 1}`,
+			method: "GET",
+			path:   "/v1/projects/p/things/a%20b",
+			query: url.Values{
+				"view":         {"FULL"},
+				"pageSize":     {"10"},
+				"ids":          {"1", "2"},
+				"tags":         {"x"},
+				"filter.query": {"q"},
+				"filter.raw":   {"/w=="},
+				"filter.exact": {"true"},
+			},
+			params: "name=projects%2Fp%2Fthings%2Fa+b",
+			want:   &mypackagepb.Thing{Name: "projects/p/things/a b", Size: 3},
+		},
+		{
+			name: "whole request in body",
+			call: func() (proto.Message, error) {
+				return c.CreateThing(ctx, &mypackagepb.Thing{Name: "a", Size: 42})
+			},
+			status: http.StatusOK,
+			resp:   `{"name": "a"}`,
+			method: "POST",
+			path:   "/v1/things/42",
+			body:   `{"name":"a","size":"42"}`,
+			params: "size=42",
+			want:   &mypackagepb.Thing{Name: "a"},
+		},
+		{
+			name: "field in body",
+			call: func() (proto.Message, error) {
+				return c.UpdateThing(ctx, &mypackagepb.UpdateThingRequest{
+					Thing:      &mypackagepb.Thing{Name: "projects/p/things/a", Size: 1},
+					UpdateMask: &field_mask.FieldMask{Paths: []string{"name", "size"}},
+				})
+			},
+			status: http.StatusOK,
+			resp:   `{"name": "projects/p/things/a", "size": "1"}`,
+			method: "PATCH",
+			path:   "/v1/projects/p/things/a",
+			body:   `{"name":"projects/p/things/a","size":"1"}`,
+			query:  url.Values{"updateMask": {"name,size"}},
+			params: "thing.name=projects%2Fp%2Fthings%2Fa",
+			want:   &mypackagepb.Thing{Name: "projects/p/things/a", Size: 1},
+		},
+		{
+			name: "custom method and empty response",
+			call: func() (proto.Message, error) {
+				return nil, c.PurgeThing(ctx, &mypackagepb.Thing{Name: "a/b"})
+			},
+			status: http.StatusOK,
+			method: "PURGE",
+			path:   "/v1/a%2Fb:purge",
+			params: "name=a%2Fb",
+		},
+		{
+			name: "error status",
+			call: func() (proto.Message, error) {
+				return c.GetThing(ctx, &mypackagepb.GetThingRequest{Name: "projects/p/things/a"})
+			},
+			status: http.StatusNotFound,
+			resp:   `{"error": {"code": 404, "message": "no thing a", "status": "NOT_FOUND"}}`,
+			method: "GET",
+			path:   "/v1/projects/p/things/a",
+			params: "name=projects%2Fp%2Fthings%2Fa",
+			code:   codes.NotFound,
+		},
+		{
+			name: "error status without JSON",
+			call: func() (proto.Message, error) {
+				return c.CreateThing(ctx, &mypackagepb.Thing{Name: "a"})
+			},
+			status: http.StatusServiceUnavailable,
+			resp:   "overloaded",
+			method: "POST",
+			path:   "/v1/things/0",
+			body:   `{"name":"a"}`,
+			params: "size=0",
+			code:   codes.Unavailable,
+		},
+	} {
+		gotMethod, gotPath, gotQuery, gotBody, gotType, gotParams, gotClient = "", "", nil, "", "", "", ""
+		respStatus, respBody = tst.status, tst.resp
+
+		got, err := tst.call()
+		if tst.code != codes.OK {
+			if status.Code(err) != tst.code {
+				t.Errorf("%s: got error %v, want code %s", tst.name, err, tst.code)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tst.name, err)
+		} else if tst.want != nil && !proto.Equal(got, tst.want) {
+			t.Errorf("%s: got response %v, want %v", tst.name, got, tst.want)
+		}
+
+		if gotMethod != tst.method || gotPath != tst.path {
+			t.Errorf("%s: got request %s %s, want %s %s", tst.name, gotMethod, gotPath, tst.method, tst.path)
+		}
+		if tst.query == nil {
+			tst.query = url.Values{}
+		}
+		if !reflect.DeepEqual(gotQuery, tst.query) {
+			t.Errorf("%s: got query %v, want %v", tst.name, gotQuery, tst.query)
+		}
+		if gotBody != tst.body {
+			t.Errorf("%s: got body %q, want %q", tst.name, gotBody, tst.body)
+		}
+		if wantType := "application/json"; tst.body != "" && gotType != wantType {
+			t.Errorf("%s: got content type %q, want %q", tst.name, gotType, wantType)
+		}
+		if gotParams != tst.params {
+			t.Errorf("%s: got x-goog-request-params %q, want %q", tst.name, gotParams, tst.params)
+		}
+		if !strings.Contains(gotClient, "gapic/") {
+			t.Errorf("%s: got x-goog-api-client %q, want the version of the client", tst.name, gotClient)
+		}
+	}
+
+	// The message of the error is the one of the JSON error, if any.
+	respStatus, respBody = http.StatusNotFound, `{"error": {"message": "no thing a"}}`
+	if _, err := c.GetThing(ctx, &mypackagepb.GetThingRequest{Name: "a"}); status.Convert(err).Message() != "no thing a" {
+		t.Errorf("got error %v, want message %q", err, "no thing a")
+	}
+
+	// Methods that cannot be sent over REST.
+	if _, err := c.SecretThing(ctx, &mypackagepb.Thing{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("SecretThing without HTTP mapping: got error %v, want code %s", err, codes.Unimplemented)
+	}
+	if _, err := c.LongThing(ctx, &mypackagepb.Thing{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("LongThing returning an operation: got error %v, want code %s", err, codes.Unimplemented)
+	}
+	if _, err := c.ServerThings(ctx, &mypackagepb.Thing{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("ServerThings streaming: got error %v, want code %s", err, codes.Unimplemented)
+	}
+}
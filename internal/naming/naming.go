@@ -0,0 +1,110 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package naming derives Go identifiers, file names and JSON names from protobuf names,
+// following the rules of protoc-gen-go so that generated code refers to the generated
+// protobuf types correctly.
+package naming
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CamelCase returns the Go name protoc-gen-go gives to the protobuf element named s,
+// for example a field or a oneof.
+//
+// An underscore followed by a lower-case letter is removed and the letter is upper-cased,
+// as is the first letter. Other characters are kept, so "foo_ID_list" gives "Foo_IDList"
+// and "SNAKE_CASE" is unchanged. A leading underscore is replaced by "X".
+func CamelCase(s string) string {
+	if s == "" {
+		return ""
+	}
+	t := make([]byte, 0, len(s)+1)
+	i := 0
+	if s[0] == '_' {
+		// Need a capital letter; drop the '_'.
+		t = append(t, 'X')
+		i++
+	}
+	// Invariant: if the next letter is lower case, it must be converted to upper case.
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '_' && i+1 < len(s) && isASCIILower(s[i+1]) {
+			continue
+		}
+		if isASCIIDigit(c) {
+			t = append(t, c)
+			continue
+		}
+		if isASCIILower(c) {
+			c ^= ' '
+		}
+		t = append(t, c)
+		// Accept the lower-case sequence that follows.
+		for i+1 < len(s) && isASCIILower(s[i+1]) {
+			i++
+			t = append(t, s[i])
+		}
+	}
+	return string(t)
+}
+
+// Snake converts the CamelCase name s to snake_case.
+// Acronyms are kept together, so "HTTPService" gives "http_service",
+// and digits stay with the preceding word, so "LoggingServiceV2" gives "logging_service_v2".
+func Snake(s string) string {
+	rs := []rune(s)
+	var sb strings.Builder
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+// JSONName returns the JSON name protoc gives to the field named s when it is not set explicitly.
+// Underscores are removed and the letter following each one is upper-cased,
+// so "foo_ID_list" gives "fooIDList".
+func JSONName(s string) string {
+	var sb strings.Builder
+	up := false
+	for _, r := range s {
+		switch {
+		case r == '_':
+			up = true
+		case up:
+			sb.WriteRune(unicode.ToUpper(r))
+			up = false
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package naming
+
+import "testing"
+
+func TestCamelCase(t *testing.T) {
+	for _, tst := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"one", "One"},
+		{"one_two", "OneTwo"},
+		{"_my_field_name_2", "XMyFieldName_2"},
+		{"Something_Capped", "Something_Capped"},
+		{"my_Name", "My_Name"},
+		{"OneTwo", "OneTwo"},
+		{"_", "X"},
+		{"_a_", "XA_"},
+		{"foo_ID_list", "Foo_IDList"},
+		{"page_size", "PageSize"},
+		{"ip_v4_address", "IpV4Address"},
+		{"int64", "Int64"},
+	} {
+		if got := CamelCase(tst.in); got != tst.want {
+			t.Errorf("CamelCase(%q) = %q, want %q", tst.in, got, tst.want)
+		}
+	}
+}
+
+func TestSnake(t *testing.T) {
+	for _, tst := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"Foo", "foo"},
+		{"FooBar", "foo_bar"},
+		{"HTTPService", "http_service"},
+		{"MyHTTP", "my_http"},
+		{"LoggingServiceV2", "logging_service_v2"},
+		{"IAMPolicy", "iam_policy"},
+		{"already_snake", "already_snake"},
+	} {
+		if got := Snake(tst.in); got != tst.want {
+			t.Errorf("Snake(%q) = %q, want %q", tst.in, got, tst.want)
+		}
+	}
+}
+
+func TestJSONName(t *testing.T) {
+	for _, tst := range []struct {
+		in, want string
+	}{
+		{"foo", "foo"},
+		{"foo_bar", "fooBar"},
+		{"foo_ID_list", "fooIDList"},
+		{"display_name_2", "displayName2"},
+	} {
+		if got := JSONName(tst.in); got != tst.want {
+			t.Errorf("JSONName(%q) = %q, want %q", tst.in, got, tst.want)
+		}
+	}
+}
@@ -131,7 +131,7 @@ func (in *Info) ImportSpec(e proto.Message) (ImportSpec, error) {
 
 	pkg := fdesc.GetOptions().GetGoPackage()
 	if pkg == "" {
-		return ImportSpec{}, errors.E(nil, "can't determine import path for %v, file %q missing `option go_package`", eTxt, fdesc.GetName())
+		return ImportSpec{}, errors.User(nil, "can't determine import path for %v, file %q missing `option go_package`", eTxt, fdesc.GetName())
 	}
 
 	if p := strings.IndexByte(pkg, ';'); p >= 0 {